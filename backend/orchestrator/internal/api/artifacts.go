@@ -0,0 +1,234 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Davanesh/auto-orchestrator/internal/artifacts"
+	"github.com/Davanesh/auto-orchestrator/internal/db"
+	"github.com/Davanesh/auto-orchestrator/internal/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Node data values larger than the threshold are spilled to the artifact
+// store before a run or workflow document is written. Configuration:
+//
+//	ARTIFACT_STORE             "fs" (default) or "gridfs"
+//	ARTIFACT_DIR               directory of the fs store, default "artifacts"
+//	ARTIFACT_THRESHOLD_BYTES   spill threshold, default 256 KiB
+
+var artifactBackend struct {
+	once      sync.Once
+	store     artifacts.Store
+	threshold int
+	err       error
+}
+
+// artifactStore returns the configured store, set up on first use.
+func artifactStore() (artifacts.Store, int, error) {
+	b := &artifactBackend
+	b.once.Do(func() {
+		b.threshold = artifacts.DefaultThreshold
+		if v := os.Getenv("ARTIFACT_THRESHOLD_BYTES"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				b.err = fmt.Errorf("invalid ARTIFACT_THRESHOLD_BYTES %q", v)
+				return
+			}
+			b.threshold = n
+		}
+
+		switch kind := os.Getenv("ARTIFACT_STORE"); kind {
+		case "", "fs":
+			dir := os.Getenv("ARTIFACT_DIR")
+			if dir == "" {
+				dir = "artifacts"
+			}
+			b.store, b.err = artifacts.NewFSStore(dir)
+		case "gridfs":
+			b.store = artifacts.NewGridFSStore(db.GetDatabase(), "artifacts")
+		default:
+			b.err = fmt.Errorf("unknown ARTIFACT_STORE %q", kind)
+		}
+		if b.err != nil {
+			log.Printf("❌ Artifact store unavailable: %v", b.err)
+		}
+	})
+	return b.store, b.threshold, b.err
+}
+
+// spillData returns data with every value above the threshold replaced by an
+// artifact ref. data itself is not modified. If the store fails, the value
+// is kept inline.
+func spillData(runID, nodeID string, data map[string]interface{}) map[string]interface{} {
+	store, threshold, err := artifactStore()
+	if err != nil {
+		return data
+	}
+
+	var out map[string]interface{}
+	for k, v := range data {
+		if _, ok := artifacts.AsRef(v); ok || artifacts.Size(v) <= threshold {
+			continue
+		}
+		ref, err := spill(store, runID, nodeID, k, v)
+		if err != nil {
+			log.Printf("⚠️ Failed to spill %s.%s of run %s: %v", nodeID, k, runID, err)
+			continue
+		}
+		if out == nil {
+			out = make(map[string]interface{}, len(data))
+			for k2, v2 := range data {
+				out[k2] = v2
+			}
+		}
+		out[k] = ref.Map()
+	}
+	if out == nil {
+		return data
+	}
+	return out
+}
+
+// spill writes one value to the store and records its metadata.
+func spill(store artifacts.Store, runID, nodeID, key string, v interface{}) (artifacts.Ref, error) {
+	content, contentType, err := artifacts.Encode(v)
+	if err != nil {
+		return artifacts.Ref{}, err
+	}
+
+	id := artifacts.ID(runID, nodeID, key, content)
+	ref := artifacts.Ref{
+		ID:          id,
+		ContentType: contentType,
+		Size:        int64(len(content)),
+		URL:         fmt.Sprintf("/runs/%s/artifacts/%s", runID, id),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// IDs are derived from the content and the metadata is written after
+	// the content, so an existing document means the artifact is stored: a
+	// node whose data is checkpointed several times is uploaded once.
+	collection := db.GetCollection("artifacts")
+	n, err := collection.CountDocuments(ctx, bson.M{"_id": id}, options.Count().SetLimit(1))
+	if err != nil {
+		return artifacts.Ref{}, err
+	}
+	if n > 0 {
+		return ref, nil
+	}
+
+	if err := store.Put(ctx, id, bytes.NewReader(content)); err != nil {
+		return artifacts.Ref{}, err
+	}
+	meta := models.Artifact{
+		ID:          id,
+		RunID:       runID,
+		NodeID:      nodeID,
+		Key:         key,
+		ContentType: contentType,
+		Size:        ref.Size,
+		CreatedAt:   time.Now(),
+	}
+	_, err = collection.ReplaceOne(ctx,
+		bson.M{"_id": id}, meta, options.Replace().SetUpsert(true))
+	if err != nil {
+		return artifacts.Ref{}, err
+	}
+
+	log.Printf("📦 Spilled %s.%s of run %s to artifact %s (%d bytes)", nodeID, key, runID, id, ref.Size)
+	return ref, nil
+}
+
+// loadArtifacts replaces the artifact refs in data with their content, so a
+// resumed run sees the values its nodes produced.
+func loadArtifacts(data map[string]interface{}) {
+	for k, v := range data {
+		ref, ok := artifacts.AsRef(v)
+		if !ok {
+			continue
+		}
+		store, _, err := artifactStore()
+		if err != nil {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		value, err := artifacts.Load(ctx, store, ref)
+		cancel()
+		if err != nil {
+			log.Printf("⚠️ Failed to load artifact %s: %v", ref.ID, err)
+			continue
+		}
+		data[k] = value
+	}
+}
+
+// -----------------------------------------------------
+// RUN ARTIFACTS
+// -----------------------------------------------------
+
+// GetRunArtifacts lists the artifacts of a run.
+func GetRunArtifacts(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	cursor, err := db.GetCollection("artifacts").Find(ctx, bson.M{"runId": c.Param("runId")}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	list := []models.Artifact{}
+	if err := cursor.All(ctx, &list); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// DownloadArtifact serves the content of one artifact of a run.
+func DownloadArtifact(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var meta models.Artifact
+	filter := bson.M{"_id": c.Param("artifactId"), "runId": c.Param("runId")}
+	if err := db.GetCollection("artifacts").FindOne(ctx, filter).Decode(&meta); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Artifact not found"})
+		return
+	}
+
+	store, _, err := artifactStore()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	rc, err := store.Get(ctx, meta.ID)
+	if errors.Is(err, artifacts.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Artifact content is missing"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rc.Close()
+
+	c.DataFromReader(http.StatusOK, meta.Size, meta.ContentType, rc, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": meta.NodeID + "-" + meta.Key}),
+	})
+}
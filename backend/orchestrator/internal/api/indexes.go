@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Davanesh/auto-orchestrator/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indexes are the indexes the handlers rely on for correctness, not just
+// speed: unique keys that turn concurrent inserts into duplicate-key errors.
+var indexes = []struct {
+	collection string
+	model      mongo.IndexModel
+}{
+	{"secrets", mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}},
+	// One recording per executor attempt; see models.Recording.
+	{"recordings", mongo.IndexModel{
+		Keys:    bson.D{{Key: "runId", Value: 1}, {Key: "nodeId", Value: 1}, {Key: "seq", Value: 1}, {Key: "attempt", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}},
+}
+
+// EnsureIndexes creates the indexes above. Call it once at startup, after
+// db.InitDB. A failure is logged, not fatal: it usually means existing
+// documents violate the index and need cleaning up.
+func EnsureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, ix := range indexes {
+		if _, err := db.GetCollection(ix.collection).Indexes().CreateOne(ctx, ix.model); err != nil {
+			log.Printf("⚠️ Failed to create index on %s: %v", ix.collection, err)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Davanesh/auto-orchestrator/internal/db"
+	"github.com/Davanesh/auto-orchestrator/internal/models"
+	"github.com/Davanesh/auto-orchestrator/internal/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// -----------------------------------------------------
+// RECORDINGS
+// -----------------------------------------------------
+
+// GetRunRecordings returns the inputs and outputs of every executor attempt
+// of a run, oldest first.
+func GetRunRecordings(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	recs, err := loadRecordings(ctx, c.Param("runId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, recs)
+}
+
+// loadRecordings reads the recordings of a run with their spilled values
+// loaded back.
+func loadRecordings(ctx context.Context, runID string) ([]models.Recording, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := db.GetCollection("recordings").Find(ctx, bson.M{"runId": runID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	recs := []models.Recording{}
+	if err := cursor.All(ctx, &recs); err != nil {
+		return nil, err
+	}
+	for i := range recs {
+		loadArtifacts(recs[i].Input)
+		loadArtifacts(recs[i].Output)
+	}
+	return recs, nil
+}
+
+// recordedSeqs returns, for every node of a run that has recordings, the
+// execution count its next recording must use.
+func recordedSeqs(ctx context.Context, runID string) (map[string]int, error) {
+	cursor, err := db.GetCollection("recordings").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"runId": runID}}},
+		{{Key: "$group", Value: bson.M{"_id": "$nodeId", "seq": bson.M{"$max": "$seq"}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		NodeID string `bson:"_id"`
+		Seq    int    `bson:"seq"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	next := make(map[string]int, len(rows))
+	for _, r := range rows {
+		next[r.NodeID] = r.Seq + 1
+	}
+	return next, nil
+}
+
+// -----------------------------------------------------
+// REPLAY
+// -----------------------------------------------------
+
+// ReplayRun re-executes a finished run from its recordings. Nodes with side
+// effects (AI, WhatsApp, state, waits, subworkflows...) return what they
+// returned originally; every other node runs again. The replay is a new run
+// with ReplayOf set; once it finishes, its Divergences list where control
+// flow or data differed from the original.
+func ReplayRun(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("runId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid run id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var original models.Run
+	if err := db.GetCollection("runs").FindOne(ctx, bson.M{"_id": objectID}).Decode(&original); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Run not found"})
+		return
+	}
+	switch original.Status {
+	case "queued", "running", "paused":
+		c.JSON(http.StatusConflict, gin.H{"error": "Run has not finished", "status": original.Status})
+		return
+	}
+	if len(original.Definition) == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Run was not recorded and cannot be replayed"})
+		return
+	}
+
+	recs, err := loadRecordings(ctx, original.ID.Hex())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	run := &models.Run{
+		ID:             primitive.NewObjectID(),
+		WorkflowID:     original.WorkflowID,
+		Status:         "queued",
+		Input:          original.Input,
+		OutputDefs:     original.OutputDefs,
+		Nodes:          original.Definition,
+		Definition:     original.Definition,
+		Start:          original.Start,
+		MaxConcurrency: original.MaxConcurrency,
+		ReplayOf:       original.ID.Hex(),
+		CreatedAt:      time.Now(),
+	}
+	if _, err := db.GetCollection("runs").InsertOne(ctx, run); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	graph := graphFromRun(run)
+	graph.Replay = services.NewReplay(recs)
+	trackRun(run, graph)
+
+	log.Printf("⏪ Replaying run %s as %s", original.ID.Hex(), run.ID.Hex())
+	go func() {
+		executeRun(context.Background(), run, graph)
+		reportDivergences(run, recs)
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"runId":    run.ID.Hex(),
+		"replayOf": run.ReplayOf,
+		"status":   run.Status,
+	})
+}
+
+// reportDivergences compares a finished replay with the run it replays and
+// stores the differences on the replay's run record.
+func reportDivergences(run *models.Run, original []models.Recording) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	replayed, err := loadRecordings(ctx, run.ID.Hex())
+	if err != nil {
+		log.Printf("⚠️ Failed to load recordings of replay %s: %v", run.ID.Hex(), err)
+		return
+	}
+
+	divs := services.CompareRecordings(original, replayed)
+	if divs == nil {
+		divs = []models.Divergence{}
+	}
+	// The compared values were loaded back from artifacts; spill them again
+	// so the divergences fit in the run record.
+	for i := range divs {
+		divs[i].Expected = spillDivergence(run.ID.Hex(), divs[i].NodeID, divs[i].Expected)
+		divs[i].Actual = spillDivergence(run.ID.Hex(), divs[i].NodeID, divs[i].Actual)
+	}
+	updateRun(run.ID, bson.M{"divergences": divs})
+
+	if len(divs) == 0 {
+		log.Printf("✅ Replay %s matches run %s", run.ID.Hex(), run.ReplayOf)
+		return
+	}
+	log.Printf("🔍 Replay %s diverges from run %s in %d place(s), first: %s",
+		run.ID.Hex(), run.ReplayOf, len(divs), divs[0].Message)
+}
+
+// spillDivergence spills the large values of a divergence's changed keys.
+func spillDivergence(runID, nodeID string, v interface{}) interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		return spillData(runID, nodeID, m)
+	}
+	return v
+}
@@ -0,0 +1,595 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Davanesh/auto-orchestrator/internal/db"
+	"github.com/Davanesh/auto-orchestrator/internal/executors"
+	"github.com/Davanesh/auto-orchestrator/internal/models"
+	"github.com/Davanesh/auto-orchestrator/internal/secrets"
+	"github.com/Davanesh/auto-orchestrator/internal/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func RegisterRunRoutes(r *gin.Engine) {
+	r.GET("/runs/:runId", GetRun)
+	r.GET("/runs/:runId/logs", GetRunLogs)
+	r.GET("/workflows/:id/runs", GetWorkflowRuns)
+	r.POST("/runs/:runId/pause", PauseRun)
+	r.POST("/runs/:runId/resume", ResumeRun)
+	r.POST("/runs/:runId/cancel", CancelRun)
+	r.GET("/runs/:runId/artifacts", GetRunArtifacts)
+	r.GET("/runs/:runId/artifacts/:artifactId", DownloadArtifact)
+	r.GET("/runs/:runId/recordings", GetRunRecordings)
+	r.POST("/runs/:runId/replay", ReplayRun)
+}
+
+// -----------------------------------------------------
+// GET RUN BY ID
+// -----------------------------------------------------
+
+func GetRun(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("runId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid run id"})
+		return
+	}
+
+	collection := db.GetCollection("runs")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var run models.Run
+	if err := collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&run); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Run not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// -----------------------------------------------------
+// GET RUN LOGS
+// -----------------------------------------------------
+
+// GetRunLogs returns the execution log of a run: one entry per executor
+// attempt, oldest first.
+func GetRunLogs(c *gin.Context) {
+	collection := db.GetCollection("execution_logs")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"runId": c.Param("runId")}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logs := []models.ExecutionLog{}
+	if err := cursor.All(ctx, &logs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, logs)
+}
+
+// -----------------------------------------------------
+// LIST RUNS OF A WORKFLOW
+// -----------------------------------------------------
+
+// GetWorkflowRuns returns the most recent runs first. ?limit= caps the
+// result (default 50).
+func GetWorkflowRuns(c *gin.Context) {
+	limit := int64(50)
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = n
+	}
+
+	collection := db.GetCollection("runs")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, bson.M{"workflowId": c.Param("id")}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	runs := []models.Run{}
+	if err := cursor.All(ctx, &runs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// -----------------------------------------------------
+// PAUSE / RESUME / CANCEL
+// -----------------------------------------------------
+
+// PauseRun stops a run from starting new nodes. Nodes already running
+// finish; the run and its active child runs stay "paused" until resumed.
+func PauseRun(c *gin.Context) {
+	runID := c.Param("runId")
+	runs := activeTree(runID)
+	if len(runs) == 0 {
+		inactiveRun(c, runID)
+		return
+	}
+	if !runs[0].graph.Pause() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Run is already paused"})
+		return
+	}
+	for _, ar := range runs[1:] {
+		ar.graph.Pause()
+	}
+	for _, ar := range runs {
+		updateRun(ar.id, bson.M{"status": "paused"})
+	}
+
+	log.Printf("⏸️ Run %s paused", runID)
+	c.JSON(http.StatusOK, gin.H{"runId": runID, "status": "paused"})
+}
+
+// ResumeRun lets a paused run schedule nodes again.
+func ResumeRun(c *gin.Context) {
+	runID := c.Param("runId")
+	runs := activeTree(runID)
+	if len(runs) == 0 {
+		inactiveRun(c, runID)
+		return
+	}
+	if !runs[0].graph.Resume() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Run is not paused"})
+		return
+	}
+	for _, ar := range runs[1:] {
+		ar.graph.Resume()
+	}
+	for _, ar := range runs {
+		updateRun(ar.id, bson.M{"status": "running"})
+	}
+
+	log.Printf("▶️ Run %s resumed", runID)
+	c.JSON(http.StatusOK, gin.H{"runId": runID, "status": "running"})
+}
+
+// CancelRun aborts a run: in-flight executors are cancelled, WhatsApp and
+// Telegram waiters of the run and its child runs are removed and nodes that
+// never started end up "cancelled". The run record is finalised by
+// executeRun.
+func CancelRun(c *gin.Context) {
+	runID := c.Param("runId")
+	runs := activeTree(runID)
+	if len(runs) == 0 {
+		inactiveRun(c, runID)
+		return
+	}
+
+	runs[0].cancel()
+	for _, ar := range runs {
+		executors.CancelWaiters(ar.id.Hex())
+	}
+
+	log.Printf("🛑 Run %s cancelled", runID)
+	c.JSON(http.StatusAccepted, gin.H{"runId": runID, "status": "cancelled"})
+}
+
+// inactiveRun answers a control request for a run that is not executing in
+// this process.
+func inactiveRun(c *gin.Context, runID string) {
+	objectID, err := primitive.ObjectIDFromHex(runID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid run id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var run models.Run
+	if err := db.GetCollection("runs").FindOne(ctx, bson.M{"_id": objectID}).Decode(&run); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Run not found"})
+		return
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": "Run is not active", "status": run.Status})
+}
+
+// -----------------------------------------------------
+// ACTIVE RUNS
+// -----------------------------------------------------
+
+// activeRun is a run executing in this process.
+type activeRun struct {
+	id     primitive.ObjectID
+	graph  *services.ExecGraph
+	cancel context.CancelFunc
+}
+
+var activeRuns = struct {
+	sync.Mutex
+	m map[string]*activeRun
+}{m: map[string]*activeRun{}}
+
+// activeTree returns the active run with the given id followed by its
+// active descendants (subworkflow child runs), or nil if it is not active.
+func activeTree(runID string) []*activeRun {
+	activeRuns.Lock()
+	defer activeRuns.Unlock()
+
+	root, ok := activeRuns.m[runID]
+	if !ok {
+		return nil
+	}
+
+	tree := []*activeRun{root}
+	for i := 0; i < len(tree); i++ {
+		parent := tree[i].id.Hex()
+		for _, ar := range activeRuns.m {
+			if ar.graph.ParentRunID == parent {
+				tree = append(tree, ar)
+			}
+		}
+	}
+	return tree
+}
+
+// -----------------------------------------------------
+// RUN RECORDS
+// -----------------------------------------------------
+
+// createRun stores a queued run for graph and wires the graph so that every
+// node transition is checkpointed to the run record.
+func createRun(ctx context.Context, id primitive.ObjectID, wf *models.Workflow, graph *services.ExecGraph) (*models.Run, error) {
+	run := &models.Run{
+		ID:             id,
+		WorkflowID:     wf.ID.Hex(),
+		Status:         "queued",
+		Input:          graph.Input,
+		OutputDefs:     graph.OutputDefs,
+		Nodes:          []models.RunNode{},
+		Start:          graph.Start,
+		MaxConcurrency: graph.MaxConcurrency,
+		ParentRunID:    graph.ParentRunID,
+		ParentNodeID:   graph.ParentNodeID,
+		Depth:          graph.Depth,
+		CreatedAt:      time.Now(),
+	}
+
+	seen := map[string]bool{}
+	for _, node := range wf.Nodes {
+		n, ok := graph.Nodes[node.CanvasID]
+		if !ok || seen[n.ID] {
+			continue
+		}
+		seen[n.ID] = true
+		run.Nodes = append(run.Nodes, toRunNode(run.ID.Hex(), n))
+	}
+
+	run.Definition = append([]models.RunNode(nil), run.Nodes...)
+
+	if _, err := db.GetCollection("runs").InsertOne(ctx, run); err != nil {
+		return nil, err
+	}
+
+	trackRun(run, graph)
+	return run, nil
+}
+
+// trackRun checkpoints every node transition of graph into the run record
+// and writes one execution log entry and one recording per executor attempt.
+func trackRun(run *models.Run, graph *services.ExecGraph) {
+	index := map[string]int{}
+	for i, n := range run.Nodes {
+		index[n.ID] = i
+	}
+
+	graph.OnNodeUpdate = func(n *services.ExecNode) {
+		i, ok := index[n.ID]
+		if !ok {
+			return
+		}
+		updateRun(run.ID, bson.M{fmt.Sprintf("nodes.%d", i): toRunNode(run.ID.Hex(), n)})
+	}
+
+	graph.OnAttempt = func(n *services.ExecNode, attempt int, started time.Time, err error) {
+		entry := models.ExecutionLog{
+			WorkflowID: run.WorkflowID,
+			RunID:      run.ID.Hex(),
+			NodeID:     n.ID,
+			TaskName:   n.Label,
+			Status:     "completed",
+			Attempt:    attempt,
+			DurationMs: time.Since(started).Milliseconds(),
+			Timestamp:  time.Now(),
+			Details:    bson.M{"type": n.Type},
+		}
+		if err != nil {
+			entry.Status = "failed"
+			entry.Error = secrets.Redact(err.Error())
+			entry.ErrorClass = services.ClassifyError(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := db.GetCollection("execution_logs").InsertOne(ctx, entry); err != nil {
+			log.Printf("⚠️ Failed to write execution log for %s: %v", n.ID, err)
+		}
+	}
+
+	graph.OnRecord = func(rec models.Recording) {
+		rec.RunID = run.ID.Hex()
+		rec.Input = spillData(rec.RunID, rec.NodeID, secrets.RedactData(rec.Input))
+		rec.Output = spillData(rec.RunID, rec.NodeID, secrets.RedactData(rec.Output))
+		rec.Error = secrets.Redact(rec.Error)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := db.GetCollection("recordings").InsertOne(ctx, rec); err != nil {
+			log.Printf("⚠️ Failed to write recording for %s: %v", rec.NodeID, err)
+		}
+	}
+}
+
+// executeRun drives a run to completion. Top-level runs are started on their
+// own goroutine with a background context and outlive the HTTP request that
+// created them; child runs of a subworkflow use the parent node's context.
+func executeRun(ctx context.Context, run *models.Run, graph *services.ExecGraph) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	activeRuns.Lock()
+	activeRuns.m[run.ID.Hex()] = &activeRun{id: run.ID, graph: graph, cancel: cancel}
+	activeRuns.Unlock()
+	defer func() {
+		activeRuns.Lock()
+		delete(activeRuns.m, run.ID.Hex())
+		activeRuns.Unlock()
+	}()
+
+	set := bson.M{"status": "running"}
+	if graph.Paused() {
+		set["status"] = "paused"
+	}
+	if run.StartedAt == nil {
+		set["startedAt"] = time.Now()
+	}
+	updateRun(run.ID, set)
+
+	log.Printf("🔥 Running workflow %s (run=%s)", run.WorkflowID, graph.RunID)
+
+	err := services.RunWorkflow(ctx, graph)
+
+	status := "completed"
+	set = bson.M{"finishedAt": time.Now()}
+	if outputs, outErr := graph.Outputs(); outErr != nil {
+		log.Printf("⚠️ Failed to resolve outputs of run %s: %v", graph.RunID, outErr)
+	} else {
+		set["outputs"] = spillData(graph.RunID, "outputs", secrets.RedactData(outputs))
+	}
+	if err != nil {
+		log.Println("❌ Engine error:", err)
+		status = "failed"
+		if ctx.Err() != nil {
+			status = "cancelled"
+		}
+		set["error"] = secrets.Redact(err.Error())
+	}
+	set["status"] = status
+	updateRun(run.ID, set)
+
+	syncWorkflowNodes(run.WorkflowID, graph, status)
+	return err
+}
+
+// syncWorkflowNodes copies the latest run's node results into the workflow
+// document so the canvas can show node statuses.
+func syncWorkflowNodes(workflowID string, graph *services.ExecGraph, status string) {
+	objectID, err := primitive.ObjectIDFromHex(workflowID)
+	if err != nil {
+		return
+	}
+
+	collection := db.GetCollection("workflows")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var wf models.Workflow
+	if err := collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&wf); err != nil {
+		log.Printf("⚠️ Workflow %s not found while saving run results", workflowID)
+		return
+	}
+
+	for i := range wf.Nodes {
+		n := &wf.Nodes[i]
+		id := n.CanvasID
+		if id == "" {
+			id = n.LegacyID
+		}
+		if updated, ok := graph.Nodes[id]; ok {
+			n.Status = updated.Status
+			n.Data = spillData(graph.RunID, id, secrets.RedactData(updated.Data))
+		}
+	}
+
+	collection.UpdateOne(ctx,
+		bson.M{"_id": objectID},
+		bson.M{"$set": bson.M{"nodes": wf.Nodes, "status": status}},
+	)
+}
+
+// -----------------------------------------------------
+// RESUME AFTER RESTART
+// -----------------------------------------------------
+
+// ResumeRuns picks up every run that was queued, running or paused when the
+// process stopped; paused runs stay paused until resumed. Each run is
+// rebuilt from its last checkpoint: finished nodes are kept, and nodes that
+// were in flight (including WhatsApp waits, which register their waiter
+// again) are re-executed with the time they had left.
+func ResumeRuns() {
+	collection := db.GetCollection("runs")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{"status": bson.M{"$in": []string{"queued", "running", "paused"}}})
+	if err != nil {
+		log.Println("❌ Failed to load unfinished runs:", err)
+		return
+	}
+
+	var runs []models.Run
+	if err := cursor.All(ctx, &runs); err != nil {
+		log.Println("❌ Failed to decode unfinished runs:", err)
+		return
+	}
+
+	for i := range runs {
+		run := &runs[i]
+
+		// A child run is owned by its parent's subworkflow node, which
+		// starts a fresh child when the parent resumes.
+		if run.ParentRunID != "" {
+			updateRun(run.ID, bson.M{
+				"status":     "cancelled",
+				"error":      "interrupted by restart; the parent run starts a new child",
+				"finishedAt": time.Now(),
+			})
+			continue
+		}
+
+		// A replay cannot pick up where it stopped without its recordings
+		// being fed again; it is cheap to start a new one.
+		if run.ReplayOf != "" {
+			updateRun(run.ID, bson.M{
+				"status":     "cancelled",
+				"error":      "interrupted by restart; replay the original run again",
+				"finishedAt": time.Now(),
+			})
+			continue
+		}
+
+		graph := graphFromRun(run)
+
+		// Re-executed nodes continue the numbering of their recordings.
+		seqs, err := recordedSeqs(ctx, run.ID.Hex())
+		if err != nil {
+			log.Printf("⚠️ Failed to load recordings of run %s: %v", run.ID.Hex(), err)
+		}
+		graph.SetSeqs(seqs)
+
+		trackRun(run, graph)
+		if run.Status == "paused" {
+			graph.Pause()
+		}
+
+		log.Printf("♻️ Resuming run %s of workflow %s", run.ID.Hex(), run.WorkflowID)
+		go executeRun(context.Background(), run, graph)
+	}
+}
+
+// graphFromRun rebuilds the execution graph from a run checkpoint.
+func graphFromRun(run *models.Run) *services.ExecGraph {
+	graph := &services.ExecGraph{
+		Nodes:          map[string]*services.ExecNode{},
+		Start:          run.Start,
+		RunID:          run.ID.Hex(),
+		WorkflowID:     run.WorkflowID,
+		MaxConcurrency: run.MaxConcurrency,
+		Input:          run.Input,
+		OutputDefs:     run.OutputDefs,
+	}
+
+	for _, rn := range run.Nodes {
+		n := &services.ExecNode{
+			ID:         rn.ID,
+			Type:       rn.Type,
+			Label:      rn.Label,
+			Data:       rn.Data,
+			Status:     rn.Status,
+			Next:       rn.Next,
+			ErrorNext:  rn.ErrorNext,
+			Body:       rn.Body,
+			Cases:      rn.Cases,
+			LambdaName: rn.LambdaName,
+			Branch:     rn.Branch,
+			Error:      rn.Error,
+			ErrorClass: rn.ErrorClass,
+			Attempts:   rn.Attempts,
+		}
+		if n.Data == nil {
+			n.Data = map[string]interface{}{}
+		}
+		loadArtifacts(n.Data)
+		if n.Next == nil {
+			n.Next = []string{}
+		}
+		if rn.StartedAt != nil {
+			n.StartedAt = *rn.StartedAt
+		}
+		if rn.FinishedAt != nil {
+			n.FinishedAt = *rn.FinishedAt
+		}
+		graph.Nodes[n.ID] = n
+	}
+
+	return graph
+}
+
+func updateRun(id primitive.ObjectID, set bson.M) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := db.GetCollection("runs").UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set}); err != nil {
+		log.Printf("⚠️ Failed to update run %s: %v", id.Hex(), err)
+	}
+}
+
+func toRunNode(runID string, n *services.ExecNode) models.RunNode {
+	rn := models.RunNode{
+		ID:         n.ID,
+		Type:       n.Type,
+		Label:      n.Label,
+		Status:     n.Status,
+		Data:       spillData(runID, n.ID, secrets.RedactData(n.Data)),
+		Next:       n.Next,
+		ErrorNext:  n.ErrorNext,
+		Body:       n.Body,
+		Cases:      n.Cases,
+		LambdaName: n.LambdaName,
+		Branch:     n.Branch,
+		Error:      secrets.Redact(n.Error),
+		ErrorClass: n.ErrorClass,
+		Attempts:   n.Attempts,
+	}
+	if !n.StartedAt.IsZero() {
+		t := n.StartedAt
+		rn.StartedAt = &t
+	}
+	if !n.FinishedAt.IsZero() {
+		t := n.FinishedAt
+		rn.FinishedAt = &t
+	}
+	return rn
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/Davanesh/auto-orchestrator/internal/db"
+	"github.com/Davanesh/auto-orchestrator/internal/models"
+	"github.com/Davanesh/auto-orchestrator/internal/secrets"
+	"github.com/Davanesh/auto-orchestrator/internal/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	services.SetSecretResolver(resolveSecrets)
+}
+
+// Secret names are used in templates ({{ secrets.name }}), so they are
+// limited to characters a template path can hold.
+var secretName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+func RegisterSecretRoutes(r *gin.Engine) {
+	r.GET("/secrets", GetSecrets)
+	r.GET("/secrets/:name", GetSecret)
+	r.POST("/secrets", CreateSecret)
+	r.PUT("/secrets/:name", UpdateSecret)
+	r.DELETE("/secrets/:name", DeleteSecret)
+}
+
+// -----------------------------------------------------
+// LIST / GET SECRETS (metadata only)
+// -----------------------------------------------------
+
+func GetSecrets(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := db.GetCollection("secrets").Find(ctx, bson.M{}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	list := []models.Secret{}
+	if err := cursor.All(ctx, &list); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func GetSecret(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var s models.Secret
+	if err := db.GetCollection("secrets").FindOne(ctx, bson.M{"name": c.Param("name")}).Decode(&s); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Secret not found"})
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+// -----------------------------------------------------
+// CREATE / UPDATE / DELETE SECRETS
+// -----------------------------------------------------
+
+func CreateSecret(c *gin.Context) {
+	var body struct {
+		Name        string `json:"name"`
+		Value       string `json:"value"`
+		Description string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return
+	}
+	if !secretName.MatchString(body.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be 1-64 letters, digits, '_' or '-'"})
+		return
+	}
+	if body.Value == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "value is required"})
+		return
+	}
+
+	nonce, ciphertext, err := secrets.Seal(body.Name, body.Value)
+	if err != nil {
+		secretsUnavailable(c, err)
+		return
+	}
+
+	now := time.Now()
+	s := models.Secret{
+		Name:        body.Name,
+		Description: body.Description,
+		Nonce:       nonce,
+		Ciphertext:  ciphertext,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The unique index on name (see EnsureIndexes) settles concurrent creates.
+	_, err = db.GetCollection("secrets").InsertOne(ctx, s)
+	if mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Secret already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	secrets.Track(body.Value)
+	log.Printf("🔐 Secret %s created", s.Name)
+	c.JSON(http.StatusCreated, s)
+}
+
+// UpdateSecret replaces a secret's value and/or description.
+func UpdateSecret(c *gin.Context) {
+	name := c.Param("name")
+
+	var body struct {
+		Value       *string `json:"value"`
+		Description *string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return
+	}
+
+	set := bson.M{"updatedAt": time.Now()}
+	if body.Description != nil {
+		set["description"] = *body.Description
+	}
+	if body.Value != nil {
+		if *body.Value == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "value must not be empty"})
+			return
+		}
+		nonce, ciphertext, err := secrets.Seal(name, *body.Value)
+		if err != nil {
+			secretsUnavailable(c, err)
+			return
+		}
+		set["nonce"] = nonce
+		set["ciphertext"] = ciphertext
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var s models.Secret
+	err := db.GetCollection("secrets").FindOneAndUpdate(ctx,
+		bson.M{"name": name},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&s)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Secret not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if body.Value != nil {
+		secrets.Track(*body.Value)
+	}
+	log.Printf("🔐 Secret %s updated", name)
+	c.JSON(http.StatusOK, s)
+}
+
+func DeleteSecret(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := db.GetCollection("secrets").DeleteOne(ctx, bson.M{"name": c.Param("name")})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if res.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Secret not found"})
+		return
+	}
+
+	log.Printf("🔐 Secret %s deleted", c.Param("name"))
+	c.JSON(http.StatusOK, gin.H{"message": "Secret deleted"})
+}
+
+// secretsUnavailable answers a request that needs the master key when it is
+// missing or unusable.
+func secretsUnavailable(c *gin.Context, err error) {
+	log.Printf("❌ Secrets store unavailable: %v", err)
+	c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+}
+
+// -----------------------------------------------------
+// RESOLUTION
+// -----------------------------------------------------
+
+// resolveSecrets decrypts the named secrets for a node about to run.
+func resolveSecrets(ctx context.Context, names []string) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := db.GetCollection("secrets").Find(ctx, bson.M{"name": bson.M{"$in": names}})
+	if err != nil {
+		return nil, err
+	}
+	var found []models.Secret
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(found))
+	for _, s := range found {
+		v, err := secrets.Open(s.Name, s.Nonce, s.Ciphertext)
+		if err != nil {
+			return nil, services.Classified(services.ErrorClassConfig, err)
+		}
+		values[s.Name] = v
+	}
+	for _, name := range names {
+		if _, ok := values[name]; !ok {
+			return nil, services.Classified(services.ErrorClassConfig, fmt.Errorf("unknown secret %q", name))
+		}
+	}
+	return values, nil
+}
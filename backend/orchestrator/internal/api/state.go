@@ -0,0 +1,196 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Davanesh/auto-orchestrator/internal/db"
+	"github.com/Davanesh/auto-orchestrator/internal/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	services.SetStateStore(mongoState{})
+}
+
+// mongoState stores state nodes' keys in the "state" collection, one
+// document per namespace and key:
+//
+//	{_id: "<ns>/<key>", ns, key, value, expiresAt?, updatedAt}
+//
+// Each operation is a single atomic update. A TTL index removes expired keys;
+// until it runs, reads and writes treat them as missing.
+type mongoState struct{}
+
+var stateIndexes sync.Once
+
+func (mongoState) collection(ctx context.Context) *mongo.Collection {
+	collection := db.GetCollection("state")
+	stateIndexes.Do(func() {
+		_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		})
+		if err != nil {
+			log.Printf("⚠️ Failed to create state TTL index: %v", err)
+		}
+	})
+	return collection
+}
+
+func stateID(ns, key string) string {
+	return ns + "/" + key
+}
+
+// live matches a key that has not expired.
+func live(now time.Time) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{"expiresAt": bson.M{"$exists": false}},
+		bson.M{"expiresAt": bson.M{"$gt": now}},
+	}}
+}
+
+// dropExpired deletes the key if it has expired, so the write that follows
+// starts from scratch. A concurrent write that renewed the key is not
+// affected: it no longer matches.
+func (s mongoState) dropExpired(ctx context.Context, id string, now time.Time) error {
+	_, err := s.collection(ctx).DeleteOne(ctx, bson.M{"_id": id, "expiresAt": bson.M{"$lte": now}})
+	return err
+}
+
+// stateUpdate adds the bookkeeping fields to an update.
+func stateUpdate(update bson.M, ns, key string, ttl time.Duration, now time.Time) bson.M {
+	set, _ := update["$set"].(bson.M)
+	if set == nil {
+		set = bson.M{}
+	}
+	set["ns"] = ns
+	set["key"] = key
+	set["updatedAt"] = now
+	if ttl > 0 {
+		set["expiresAt"] = now.Add(ttl)
+	}
+	update["$set"] = set
+	return update
+}
+
+func (s mongoState) Get(ctx context.Context, ns, key string) (interface{}, bool, error) {
+	filter := live(time.Now())
+	filter["_id"] = stateID(ns, key)
+
+	var doc bson.M
+	err := s.collection(ctx).FindOne(ctx, filter).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return doc["value"], true, nil
+}
+
+func (s mongoState) Set(ctx context.Context, ns, key string, value interface{}, ttl time.Duration) error {
+	now := time.Now()
+	update := stateUpdate(bson.M{"$set": bson.M{"value": value}}, ns, key, ttl, now)
+	if ttl <= 0 {
+		update["$unset"] = bson.M{"expiresAt": ""}
+	}
+	_, err := s.collection(ctx).UpdateOne(ctx,
+		bson.M{"_id": stateID(ns, key)}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (s mongoState) Incr(ctx context.Context, ns, key string, by float64, ttl time.Duration) (float64, error) {
+	id := stateID(ns, key)
+	now := time.Now()
+	if err := s.dropExpired(ctx, id, now); err != nil {
+		return 0, err
+	}
+
+	var doc bson.M
+	err := s.collection(ctx).FindOneAndUpdate(ctx,
+		bson.M{"_id": id},
+		stateUpdate(bson.M{"$inc": bson.M{"value": by}}, ns, key, ttl, now),
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&doc)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n := doc["value"].(type) {
+	case float64:
+		return n, nil
+	case int32:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	}
+	return 0, errors.New("state value is not a number")
+}
+
+func (s mongoState) Append(ctx context.Context, ns, key string, value interface{}, unique bool, ttl time.Duration) ([]interface{}, bool, error) {
+	id := stateID(ns, key)
+	now := time.Now()
+	if err := s.dropExpired(ctx, id, now); err != nil {
+		return nil, false, err
+	}
+
+	filter := bson.M{"_id": id}
+	if unique {
+		// Only match a list that lacks the value. If the key exists and
+		// already holds it, the upsert collides on _id: nothing was added.
+		filter["value"] = bson.M{"$ne": value}
+	}
+	update := stateUpdate(bson.M{"$push": bson.M{"value": value}}, ns, key, ttl, now)
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var doc bson.M
+	var err error
+	added := true
+	for attempt := 1; ; attempt++ {
+		err = s.collection(ctx).FindOneAndUpdate(ctx, filter, update, opts).Decode(&doc)
+		if !mongo.IsDuplicateKeyError(err) {
+			break
+		}
+		// A collision on the first attempt may also be a concurrent run
+		// creating the key; only a second one means the value is there.
+		if unique && attempt > 1 {
+			added = false
+			err = s.collection(ctx).FindOne(ctx, bson.M{"_id": id}).Decode(&doc)
+			break
+		}
+		if attempt > 1 {
+			break
+		}
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	list, ok := doc["value"].(primitive.A)
+	if !ok {
+		return nil, false, errors.New("state value is not a list")
+	}
+	return list, added, nil
+}
+
+func (s mongoState) Delete(ctx context.Context, ns, key string) (bool, error) {
+	filter := live(time.Now())
+	filter["_id"] = stateID(ns, key)
+
+	res, err := s.collection(ctx).DeleteOne(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+	if res.DeletedCount == 0 {
+		// Clean up an expired leftover, if any.
+		_, err = s.collection(ctx).DeleteOne(ctx, bson.M{"_id": stateID(ns, key)})
+	}
+	return res.DeletedCount > 0, err
+}
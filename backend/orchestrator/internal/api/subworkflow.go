@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Davanesh/auto-orchestrator/internal/db"
+	"github.com/Davanesh/auto-orchestrator/internal/models"
+	"github.com/Davanesh/auto-orchestrator/internal/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func init() {
+	services.SetSubworkflowRunner(runSubworkflow)
+}
+
+// runSubworkflow runs a saved workflow as a child of parentNode. The child
+// gets its own run record linked to the parent run in both directions, and
+// runs under the parent node's context so cancelling the parent stops it.
+func runSubworkflow(ctx context.Context, parent *services.ExecGraph, parentNode *services.ExecNode, workflowID string, input map[string]interface{}) (*services.ChildRun, error) {
+	objectID, err := primitive.ObjectIDFromHex(workflowID)
+	if err != nil {
+		return nil, services.Classified(services.ErrorClassConfig, errors.New("invalid subworkflow id: "+workflowID))
+	}
+
+	loadCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var wf models.Workflow
+	if err := db.GetCollection("workflows").FindOne(loadCtx, bson.M{"_id": objectID}).Decode(&wf); err != nil {
+		return nil, services.Classified(services.ErrorClassConfig, errors.New("subworkflow not found: "+workflowID))
+	}
+
+	runID := primitive.NewObjectID()
+	graph, err := buildExecGraph(&wf, runID.Hex())
+	if err != nil {
+		return nil, services.Classified(services.ErrorClassConfig, err)
+	}
+	if input, err = runInput(&wf, input); err != nil {
+		return nil, services.Classified(services.ErrorClassConfig, fmt.Errorf("subworkflow input: %w", err))
+	}
+	graph.Input = input
+	if err := services.ValidateGraph(graph); err != nil {
+		return nil, services.Classified(services.ErrorClassConfig, err)
+	}
+	graph.ParentRunID = parent.RunID
+	graph.ParentNodeID = parentNode.ID
+	graph.Depth = parent.Depth + 1
+	graph.MaxConcurrency = parent.MaxConcurrency
+
+	run, err := createRun(loadCtx, runID, &wf, graph)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID, err := primitive.ObjectIDFromHex(parent.RunID); err == nil {
+		pushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		db.GetCollection("runs").UpdateOne(pushCtx,
+			bson.M{"_id": parentID},
+			bson.M{"$push": bson.M{"childRunIds": runID.Hex()}},
+		)
+	}
+
+	child := &services.ChildRun{RunID: runID.Hex(), Status: "completed"}
+	err = executeRun(ctx, run, graph)
+	if err != nil {
+		child.Status = "failed"
+	}
+	outputs, outErr := graph.Outputs()
+	child.Outputs = outputs
+	if err == nil {
+		err = outErr
+	}
+	return child, err
+}
@@ -2,8 +2,12 @@ package api
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,6 +26,8 @@ func RegisterWorkflowRoutes(r *gin.Engine) {
 	r.PUT("/workflows/:id", UpdateWorkflowStatus)
 	r.POST("/workflows/:id/run", RunWorkflow)
 	r.PUT("/workflows/:id/structure", SaveWorkflowStructure)
+	r.GET("/node-types", GetNodeTypes)
+	r.POST("/transform/preview", PreviewTransform)
 }
 
 // -----------------------------------------------------
@@ -117,6 +123,11 @@ func CreateWorkflow(c *gin.Context) {
 		return
 	}
 
+	if err := validateWorkflowIO(wf.InputSchema, wf.Outputs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	wf.CreatedAt = time.Now()
 	wf.Status = "draft"
 
@@ -134,12 +145,15 @@ func CreateWorkflow(c *gin.Context) {
 // RUN WORKFLOW ENGINE
 // -----------------------------------------------------
 
+// RunWorkflow starts a run in the background and answers right away with its
+// runId. Progress and results are read back through GET /runs/:runId.
+// The optional JSON body becomes the run input.
 func RunWorkflow(c *gin.Context) {
 	id := c.Param("id")
 	objectID, _ := primitive.ObjectIDFromHex(id)
 
 	collection := db.GetCollection("workflows")
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	var wf models.Workflow
@@ -149,10 +163,66 @@ func RunWorkflow(c *gin.Context) {
 		return
 	}
 
-	graph := services.ExecGraph{
-		Nodes: map[string]*services.ExecNode{},
-		Start: "",
-		RunID: primitive.NewObjectID().Hex(),
+	var input map[string]interface{}
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON input"})
+		return
+	}
+
+	input, err = runInput(&wf, input)
+	if err != nil {
+		unprocessable(c, "Invalid run input", err)
+		return
+	}
+
+	runID := primitive.NewObjectID()
+
+	graph, err := buildExecGraph(&wf, runID.Hex())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	graph.Input = input
+
+	if err := services.ValidateGraph(graph); err != nil {
+		unprocessable(c, "Workflow nodes are invalid", err)
+		return
+	}
+
+	// Optional per-run worker limit: POST /workflows/:id/run?concurrency=8
+	if v := c.Query("concurrency"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "concurrency must be a positive integer"})
+			return
+		}
+		graph.MaxConcurrency = limit
+	}
+
+	run, err := createRun(ctx, runID, &wf, graph)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	go executeRun(context.Background(), run, graph)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"runId":      run.ID.Hex(),
+		"workflowId": wf.ID.Hex(),
+		"status":     run.Status,
+	})
+}
+
+// buildExecGraph turns a saved workflow into the engine's graph, repairing
+// node ids and connection endpoints saved by older versions of the canvas.
+func buildExecGraph(wf *models.Workflow, runID string) (*services.ExecGraph, error) {
+	graph := &services.ExecGraph{
+		Nodes:      map[string]*services.ExecNode{},
+		Start:      "",
+		RunID:      runID,
+		WorkflowID: wf.ID.Hex(),
+		OutputDefs: wf.Outputs,
 	}
 
 	// ---------------------------------------------------------
@@ -174,12 +244,16 @@ func RunWorkflow(c *gin.Context) {
 		node.CanvasID = canvas
 
 		graph.Nodes[canvas] = &services.ExecNode{
-			ID:     canvas,
-			Type:   normalizeNodeType(node.Type),
-			Label:  node.Label,
-			Data:   node.Data,
-			Status: "pending",
-			Next:   []string{},
+			ID:         canvas,
+			Type:       normalizeNodeType(node.Type),
+			Label:      node.Label,
+			Data:       node.Data,
+			Status:     "pending",
+			Next:       []string{},
+			LambdaName: node.LambdaName,
+		}
+		if graph.Nodes[canvas].Data == nil {
+			graph.Nodes[canvas].Data = map[string]interface{}{}
 		}
 
 		if strings.ToLower(node.Type) == "start" {
@@ -221,54 +295,71 @@ func RunWorkflow(c *gin.Context) {
 		}
 
 		if node, exists := graph.Nodes[src]; exists {
-			node.Next = append(node.Next, tgt)
+			switch edgeKind(conn) {
+			case "error":
+				node.ErrorNext = append(node.ErrorNext, tgt)
+			case "body":
+				node.Body = append(node.Body, tgt)
+			default:
+				node.Next = append(node.Next, tgt)
+				if name := caseName(conn); name != "" {
+					if node.Cases == nil {
+						node.Cases = map[string]string{}
+					}
+					node.Cases[tgt] = name
+				}
+			}
 		} else {
 			log.Printf("⚠️ Invalid connection source: %s -> %s", src, tgt)
 		}
 	}
 
 	if graph.Start == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No start node defined"})
-		return
+		return nil, errors.New("No start node defined")
 	}
 
-	// ---------------------------------------------------------
-	// ④ RUN ENGINE
-	// ---------------------------------------------------------
-
-	log.Println("🔥 Running NEW EXECUTION ENGINE...")
+	return graph, nil
+}
 
-	err = services.RunWorkflow(&graph)
-	if err != nil {
-		log.Println("❌ Engine error:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+// edgeKind classifies a connection by its metadata:
+//   - "error": only carries failures of its source node
+//     ({"type": "error"}, {"type": "catch"} or {"error": true})
+//   - "body": enters the body of a loop node ({"type": "body"})
+//   - "": a normal edge
+func edgeKind(conn models.Connection) string {
+	if conn.Meta == nil {
+		return ""
 	}
-
-	// ---------------------------------------------------------
-	// ⑤ SAVE NODE RESULTS BACK TO DB
-	// ---------------------------------------------------------
-
-	for i := range wf.Nodes {
-		n := &wf.Nodes[i]
-		if updated, ok := graph.Nodes[n.CanvasID]; ok {
-			n.Status = updated.Status
-			n.Data = updated.Data
+	if t, ok := conn.Meta["type"].(string); ok {
+		switch strings.ToLower(strings.TrimSpace(t)) {
+		case "error", "catch":
+			return "error"
+		case "body":
+			return "body"
 		}
 	}
+	for _, key := range []string{"error", "catch"} {
+		if b, ok := conn.Meta[key].(bool); ok && b {
+			return "error"
+		}
+	}
+	return ""
+}
 
-	wf.Status = "completed"
-
-	collection.UpdateOne(ctx,
-		bson.M{"_id": objectID},
-		bson.M{"$set": bson.M{"nodes": wf.Nodes, "status": wf.Status}},
-	)
-
-	c.JSON(http.StatusOK, gin.H{
-		"workflowId": wf.ID.Hex(),
-		"status":     wf.Status,
-		"nodes":      wf.Nodes,
-	})
+// caseName returns the case a connection is bound to: {"case": "name"} or,
+// for the default edge, {"default": true}. Switch and decision nodes pick
+// their branch by case name instead of by connection order.
+func caseName(conn models.Connection) string {
+	if conn.Meta == nil {
+		return ""
+	}
+	if b, ok := conn.Meta["default"].(bool); ok && b {
+		return "default"
+	}
+	if v, ok := conn.Meta["case"]; ok && v != nil {
+		return strings.TrimSpace(fmt.Sprintf("%v", v))
+	}
+	return ""
 }
 
 // -----------------------------------------------------
@@ -285,6 +376,24 @@ func normalizeNodeType(t string) string {
 		return "task"
 	case "decision":
 		return "decision"
+	case "loop":
+		return "loop"
+	case "subworkflow":
+		return "subworkflow"
+	case "join":
+		return "join"
+	case "switch":
+		return "switch"
+	case "transform":
+		return "transform"
+	case "state":
+		return "state"
+	case "http":
+		return "http"
+	case "lambda":
+		return "lambda"
+	case "rules":
+		return "rules"
 	case "ai":
 		return "ai"
 	case "wait":
@@ -295,6 +404,12 @@ func normalizeNodeType(t string) string {
 		return "whatsapp_static_reply"
 	case "whatsapp_send":
 		return "whatsapp_send"
+	case "telegram_send":
+		return "telegram_send"
+	case "telegram_wait":
+		return "telegram_wait"
+	case "email_send":
+		return "email_send"
 	}
 
 	return "task"
@@ -311,6 +426,10 @@ func SaveWorkflowStructure(c *gin.Context) {
 	var body struct {
 		Nodes       []models.Node       `json:"nodes"`
 		Connections []models.Connection `json:"connections"`
+
+		// Optional: only replaced when present.
+		InputSchema map[string]interface{}   `json:"inputSchema"`
+		Outputs     *[]models.WorkflowOutput `json:"outputs"`
 	}
 
 	if err := c.BindJSON(&body); err != nil {
@@ -318,19 +437,160 @@ func SaveWorkflowStructure(c *gin.Context) {
 		return
 	}
 
+	var outputs []models.WorkflowOutput
+	if body.Outputs != nil {
+		outputs = *body.Outputs
+	}
+	if err := validateWorkflowIO(body.InputSchema, outputs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validate the graph as it would run. A draft without a start node
+	// cannot be built yet; its nodes are still checked one by one.
+	draft := models.Workflow{
+		Nodes:       append([]models.Node(nil), body.Nodes...),
+		Connections: body.Connections,
+	}
+	if graph, err := buildExecGraph(&draft, ""); err == nil {
+		if err := services.ValidateGraph(graph); err != nil {
+			unprocessable(c, "Workflow nodes are invalid", err)
+			return
+		}
+	} else {
+		var fieldErrs services.ValidationErrors
+		for _, node := range body.Nodes {
+			id := node.CanvasID
+			if id == "" {
+				id = node.Label
+			}
+			fieldErrs = append(fieldErrs, services.ValidateNode(id, normalizeNodeType(node.Type), node.Data)...)
+		}
+		if len(fieldErrs) > 0 {
+			unprocessable(c, "Workflow nodes are invalid", fieldErrs)
+			return
+		}
+	}
+
 	collection := db.GetCollection("workflows")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection.UpdateOne(ctx, bson.M{"_id": objectID},
-		bson.M{"$set": bson.M{
-			"nodes":       body.Nodes,
-			"connections": body.Connections,
-		}},
-	)
+	set := bson.M{
+		"nodes":       body.Nodes,
+		"connections": body.Connections,
+	}
+	if body.InputSchema != nil {
+		set["inputSchema"] = body.InputSchema
+	}
+	if body.Outputs != nil {
+		set["outputs"] = outputs
+	}
+
+	collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": set})
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Structure saved",
 		"id":      id,
 	})
 }
+
+// unprocessable answers with the field-level errors of a workflow or run
+// input that does not match its schema.
+func unprocessable(c *gin.Context, message string, err error) {
+	var fieldErrs services.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  message,
+			"fields": fieldErrs,
+		})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// runInput validates a run's input against the workflow's input schema and
+// fills in defaults. Workflows without a schema take any input.
+func runInput(wf *models.Workflow, input map[string]interface{}) (map[string]interface{}, error) {
+	if len(wf.InputSchema) == 0 {
+		return input, nil
+	}
+	schema, err := services.ParseSchema(wf.InputSchema)
+	if err != nil {
+		return nil, err
+	}
+	return services.ValidateInput(schema, input)
+}
+
+// validateWorkflowIO checks a workflow's input schema and named outputs.
+func validateWorkflowIO(inputSchema map[string]interface{}, outputs []models.WorkflowOutput) error {
+	if len(inputSchema) > 0 {
+		if _, err := services.ParseSchema(inputSchema); err != nil {
+			return fmt.Errorf("inputSchema: %w", err)
+		}
+	}
+
+	seen := map[string]bool{}
+	for i, o := range outputs {
+		if o.Name == "" {
+			return fmt.Errorf("outputs[%d]: name is required", i)
+		}
+		if seen[o.Name] {
+			return fmt.Errorf("outputs[%d]: duplicate output %q", i, o.Name)
+		}
+		seen[o.Name] = true
+		if err := services.CheckTemplates(o.Value); err != nil {
+			return fmt.Errorf("outputs[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// -----------------------------------------------------
+// NODE TYPES
+// -----------------------------------------------------
+
+// GetNodeTypes returns the config, input and output schema of every node
+// type, keyed by type.
+func GetNodeTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, services.Schemas())
+}
+
+// -----------------------------------------------------
+// TRANSFORM PREVIEW
+// -----------------------------------------------------
+
+// PreviewTransform runs a transform mapping against sample data without
+// starting a run. The sample has the shape of the run context
+// ({"nodes": ..., "trigger": {"body": ...}, "vars": ...}); missing parts are
+// empty.
+func PreviewTransform(c *gin.Context) {
+	var body struct {
+		Mapping interface{}            `json:"mapping"`
+		Sample  map[string]interface{} `json:"sample"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Mapping == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Body must contain a mapping"})
+		return
+	}
+
+	if fieldErrs := services.ValidateMapping("", "mapping", body.Mapping); len(fieldErrs) > 0 {
+		unprocessable(c, "Mapping is invalid", services.ValidationErrors(fieldErrs))
+		return
+	}
+
+	scope := map[string]interface{}{}
+	for _, name := range services.ContextNames {
+		scope[name] = map[string]interface{}{}
+	}
+	for k, v := range body.Sample {
+		scope[k] = v
+	}
+
+	out, err := services.Transform(body.Mapping, scope)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"output": out})
+}
@@ -0,0 +1,158 @@
+package artifacts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultThreshold is the size above which a value is spilled.
+const DefaultThreshold = 256 << 10
+
+// Content types of spilled values.
+const (
+	TypeJSON   = "application/json"
+	TypeText   = "text/plain; charset=utf-8"
+	TypeBinary = "application/octet-stream"
+)
+
+// Ref replaces a spilled value in node data.
+type Ref struct {
+	ID          string `json:"artifactId" bson:"artifactId"`
+	ContentType string `json:"contentType" bson:"contentType"`
+	Size        int64  `json:"size" bson:"size"`
+	URL         string `json:"url" bson:"url"`
+}
+
+// Map returns the ref as it is stored in node data.
+func (r Ref) Map() map[string]interface{} {
+	return map[string]interface{}{
+		"artifactId":  r.ID,
+		"contentType": r.ContentType,
+		"size":        r.Size,
+		"url":         r.URL,
+	}
+}
+
+// AsRef reports whether v is a ref written by Map, possibly read back from
+// Mongo.
+func AsRef(v interface{}) (Ref, bool) {
+	var m map[string]interface{}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m = t
+	case primitive.M:
+		m = t
+	case primitive.D:
+		m = make(map[string]interface{}, len(t))
+		for _, e := range t {
+			m[e.Key] = e.Value
+		}
+	default:
+		return Ref{}, false
+	}
+	if len(m) != 4 {
+		return Ref{}, false
+	}
+	id, ok1 := m["artifactId"].(string)
+	ct, ok2 := m["contentType"].(string)
+	url, ok3 := m["url"].(string)
+	if !ok1 || !ok2 || !ok3 {
+		return Ref{}, false
+	}
+	var size int64
+	switch n := m["size"].(type) {
+	case int64:
+		size = n
+	case int32:
+		size = int64(n)
+	case int:
+		size = int64(n)
+	case float64:
+		size = int64(n)
+	default:
+		return Ref{}, false
+	}
+	return Ref{ID: id, ContentType: ct, Size: size, URL: url}, true
+}
+
+// Encode serializes a node data value: bytes as they are, strings as UTF-8
+// text and everything else as JSON.
+func Encode(v interface{}) ([]byte, string, error) {
+	switch t := v.(type) {
+	case []byte:
+		return t, TypeBinary, nil
+	case string:
+		return []byte(t), TypeText, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, "", err
+	}
+	return b, TypeJSON, nil
+}
+
+// Decode is the inverse of Encode.
+func Decode(b []byte, contentType string) (interface{}, error) {
+	switch contentType {
+	case TypeText:
+		if !utf8.Valid(b) {
+			return b, nil
+		}
+		return string(b), nil
+	case TypeJSON:
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	return b, nil
+}
+
+// Size estimates the stored size of a value without encoding strings and
+// bytes.
+func Size(v interface{}) int {
+	switch t := v.(type) {
+	case nil, bool, float64, int, int32, int64:
+		return 0
+	case []byte:
+		return len(t)
+	case string:
+		return len(t)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
+
+// ID derives an artifact's ID from where it was produced and its content, so
+// writing the same value twice yields the same artifact.
+func ID(runID, nodeID, key string, content []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00", runID, nodeID, key)
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// Load reads back and decodes the value behind ref.
+func Load(ctx context.Context, s Store, ref Ref) (interface{}, error) {
+	rc, err := s.Get(ctx, ref.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(b, ref.ContentType)
+}
@@ -0,0 +1,164 @@
+// Package artifacts keeps large node outputs and binary payloads out of the
+// run and workflow documents. Values above a size threshold are written to a
+// blob Store and replaced in node data by a Ref.
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrNotFound is returned by Store.Get for an unknown key.
+var ErrNotFound = errors.New("artifact not found")
+
+// Store is a blob store addressed by key. Put overwrites an existing blob.
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey keeps keys usable as file names.
+var validKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// -----------------------------------------------------
+// LOCAL FILESYSTEM
+// -----------------------------------------------------
+
+// FSStore keeps one file per blob in Dir.
+type FSStore struct {
+	Dir string
+}
+
+func NewFSStore(dir string) (*FSStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &FSStore{Dir: dir}, nil
+}
+
+func (s *FSStore) path(key string) (string, error) {
+	if !validKey.MatchString(key) {
+		return "", errors.New("invalid artifact key: " + key)
+	}
+	return filepath.Join(s.Dir, key), nil
+}
+
+// Put writes to a temporary file first so a reader never sees a partial blob.
+func (s *FSStore) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.Dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *FSStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *FSStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// -----------------------------------------------------
+// GRIDFS
+// -----------------------------------------------------
+
+// GridFSStore keeps blobs in a GridFS bucket, using the key as file ID.
+type GridFSStore struct {
+	db     *mongo.Database
+	bucket string
+}
+
+func NewGridFSStore(db *mongo.Database, bucket string) *GridFSStore {
+	return &GridFSStore{db: db, bucket: bucket}
+}
+
+// open returns a bucket for one operation. Buckets carry their deadlines as
+// state, so they are not shared between concurrent calls.
+func (s *GridFSStore) open(ctx context.Context) (*gridfs.Bucket, error) {
+	b, err := gridfs.NewBucket(s.db, options.GridFSBucket().SetName(s.bucket))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		b.SetReadDeadline(deadline)
+		b.SetWriteDeadline(deadline)
+	}
+	return b, nil
+}
+
+func (s *GridFSStore) Put(ctx context.Context, key string, r io.Reader) error {
+	b, err := s.open(ctx)
+	if err != nil {
+		return err
+	}
+	// GridFS files are immutable: replace any earlier blob with this key.
+	if err := b.DeleteContext(ctx, key); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+		return err
+	}
+	return b.UploadFromStreamWithID(key, key, r)
+}
+
+func (s *GridFSStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	b, err := s.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := b.OpenDownloadStream(key)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (s *GridFSStore) Delete(ctx context.Context, key string) error {
+	b, err := s.open(ctx)
+	if err != nil {
+		return err
+	}
+	if err := b.DeleteContext(ctx, key); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+		return err
+	}
+	return nil
+}
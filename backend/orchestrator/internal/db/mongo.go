@@ -39,3 +39,8 @@ func InitDB() {
 func GetCollection(name string) *mongo.Collection {
   return client.Database("auto_orchestrator").Collection(name)
 }
+
+// GetDatabase returns the orchestrator's database, e.g. for GridFS buckets.
+func GetDatabase() *mongo.Database {
+	return client.Database("auto_orchestrator")
+}
@@ -0,0 +1,240 @@
+package executors
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpSession is what the stand-in server received in one connection.
+type smtpSession struct {
+	from  string
+	rcpts []string
+	data  []byte
+}
+
+// startSMTP runs a minimal SMTP server on localhost that accepts every
+// message, standing in for MailHog and the like.
+func startSMTP(t *testing.T) (*SMTPConfig, <-chan smtpSession) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	sessions := make(chan smtpSession, 1)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, sessions)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	return &SMTPConfig{Host: host, Port: port}, sessions
+}
+
+func serveSMTP(conn net.Conn, sessions chan<- smtpSession) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	var s smtpSession
+
+	tp.PrintfLine("220 localhost stand-in")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250 localhost")
+		case "MAIL":
+			s.from = addrParam(line)
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			s.rcpts = append(s.rcpts, addrParam(line))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 end with .")
+			if s.data, err = tp.ReadDotBytes(); err != nil {
+				return
+			}
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			sessions <- s
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// addrParam extracts the address of "MAIL FROM:<a@b>" or "RCPT TO:<a@b>".
+func addrParam(line string) string {
+	i, j := strings.Index(line, "<"), strings.Index(line, ">")
+	if i < 0 || j < i {
+		return ""
+	}
+	return line[i+1 : j]
+}
+
+func TestSendEmail(t *testing.T) {
+	cfg, sessions := startSMTP(t)
+
+	pdf := []byte("%PDF-1.4\x00\x01\x02 binary \xff")
+	email := Email{
+		From:    &mail.Address{Name: "Flow Bot", Address: "bot@example.com"},
+		To:      []*mail.Address{{Name: "Ann", Address: "ann@example.com"}, {Address: "bob@example.com"}},
+		Cc:      []*mail.Address{{Address: "carol@example.com"}},
+		Bcc:     []*mail.Address{{Address: "hidden@example.com"}},
+		Subject: "Daily summary",
+		Text:    "All good.\n.\nStill good.",
+		HTML:    "<p>All good.</p>",
+		Attachments: []EmailAttachment{
+			{Filename: "report.pdf", ContentType: "application/pdf", Content: pdf},
+			{Filename: "notes.txt", ContentType: "text/plain\r\nX-Injected: 1", Content: []byte("notes")},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	id, err := SendEmail(ctx, cfg, email)
+	if err != nil {
+		t.Fatalf("SendEmail: %v", err)
+	}
+
+	var s smtpSession
+	select {
+	case s = <-sessions:
+	case <-ctx.Done():
+		t.Fatal("the SMTP stand-in received nothing")
+	}
+
+	// Envelope: every recipient, Bcc included.
+	if s.from != "bot@example.com" {
+		t.Errorf("MAIL FROM = %q", s.from)
+	}
+	want := []string{"ann@example.com", "bob@example.com", "carol@example.com", "hidden@example.com"}
+	if strings.Join(s.rcpts, ",") != strings.Join(want, ",") {
+		t.Errorf("RCPT TO = %v, want %v", s.rcpts, want)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(s.data))
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+
+	// Headers: To and Cc listed, Bcc not at all.
+	if to, _ := msg.Header.AddressList("To"); len(to) != 2 || to[0].Address != "ann@example.com" {
+		t.Errorf("To = %v", to)
+	}
+	if cc, _ := msg.Header.AddressList("Cc"); len(cc) != 1 || cc[0].Address != "carol@example.com" {
+		t.Errorf("Cc = %v", cc)
+	}
+	if msg.Header.Get("Bcc") != "" {
+		t.Errorf("Bcc header present: %q", msg.Header.Get("Bcc"))
+	}
+	head, _, _ := bytes.Cut(s.data, []byte("\r\n\r\n"))
+	if bytes.Contains(head, []byte("hidden@example.com")) {
+		t.Errorf("Bcc address leaked into the headers:\n%s", head)
+	}
+	if msg.Header.Get("Message-ID") != id {
+		t.Errorf("Message-ID = %q, SendEmail returned %q", msg.Header.Get("Message-ID"), id)
+	}
+
+	// Body: multipart/mixed of a multipart/alternative and the attachments.
+	parts := readParts(t, msg.Header.Get("Content-Type"), msg.Body)
+	if len(parts) != 3 {
+		t.Fatalf("got %d top-level parts, want 3", len(parts))
+	}
+
+	alt := parts[0]
+	alternatives := readParts(t, alt.header.Get("Content-Type"), bytes.NewReader(alt.body))
+	if len(alternatives) != 2 {
+		t.Fatalf("got %d alternatives, want 2", len(alternatives))
+	}
+	for i, w := range []struct{ mediaType, body string }{
+		{"text/plain", email.Text},
+		{"text/html", email.HTML},
+	} {
+		got := alternatives[i]
+		if mt, _, _ := mime.ParseMediaType(got.header.Get("Content-Type")); mt != w.mediaType {
+			t.Errorf("alternative %d is %s, want %s", i, mt, w.mediaType)
+		}
+		// multipart.Reader decodes quoted-printable; line breaks come back as CRLF.
+		if body := strings.ReplaceAll(string(got.body), "\r\n", "\n"); body != w.body {
+			t.Errorf("alternative %d body = %q, want %q", i, body, w.body)
+		}
+	}
+
+	for i, w := range []struct {
+		filename, contentType string
+		content               []byte
+	}{
+		{"report.pdf", "application/pdf", pdf},
+		{"notes.txt", "application/octet-stream", []byte("notes")},
+	} {
+		got := parts[i+1]
+		if ct := got.header.Get("Content-Type"); ct != w.contentType {
+			t.Errorf("attachment %d Content-Type = %q, want %q", i, ct, w.contentType)
+		}
+		if _, params, _ := mime.ParseMediaType(got.header.Get("Content-Disposition")); params["filename"] != w.filename {
+			t.Errorf("attachment %d filename = %q, want %q", i, params["filename"], w.filename)
+		}
+		content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(got.body), "\r\n", ""))
+		if err != nil {
+			t.Fatalf("attachment %d: %v", i, err)
+		}
+		if !bytes.Equal(content, w.content) {
+			t.Errorf("attachment %d content = %q, want %q", i, content, w.content)
+		}
+	}
+	if got := parts[2].header.Get("X-Injected"); got != "" {
+		t.Errorf("content type injected a header: X-Injected = %q", got)
+	}
+}
+
+type mimePart struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+func readParts(t *testing.T, contentType string, r io.Reader) []mimePart {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		t.Fatalf("Content-Type %q is not multipart", contentType)
+	}
+
+	var parts []mimePart
+	mr := multipart.NewReader(r, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return parts
+		}
+		if err != nil {
+			t.Fatalf("NextPart: %v", err)
+		}
+		body, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatalf("reading part: %v", err)
+		}
+		parts = append(parts, mimePart{header: p.Header, body: body})
+	}
+}
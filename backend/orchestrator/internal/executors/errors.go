@@ -0,0 +1,39 @@
+package executors
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// maxErrorBody bounds the upstream body kept in an HTTPError. The error text
+// ends up in node errors, execution logs and recordings, none of which are
+// spilled to artifacts.
+const maxErrorBody = 1024
+
+// HTTPError is returned when an upstream service answers with a non-2xx
+// status. The orchestrator uses the status to decide whether a retry makes
+// sense.
+type HTTPError struct {
+	Service    string
+	StatusCode int
+	Body       string
+}
+
+// NewHTTPError returns an HTTPError whose body is cut to maxErrorBody bytes.
+func NewHTTPError(service string, status int, body string) *HTTPError {
+	if len(body) > maxErrorBody {
+		cut := maxErrorBody
+		for cut > 0 && !utf8.RuneStart(body[cut]) {
+			cut--
+		}
+		body = fmt.Sprintf("%s... (%d bytes)", body[:cut], len(body))
+	}
+	return &HTTPError{Service: service, StatusCode: status, Body: body}
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s error status=%d body=%s", e.Service, e.StatusCode, e.Body)
+}
+
+// HTTPStatus returns the upstream status code.
+func (e *HTTPError) HTTPStatus() int { return e.StatusCode }
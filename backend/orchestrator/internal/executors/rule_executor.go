@@ -1 +1,295 @@
-package executors
\ No newline at end of file
+package executors
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Davanesh/auto-orchestrator/internal/expr"
+)
+
+// This file provides decision tables for the rules node:
+// - table model: RuleTable, RuleColumn, Rule
+// - compilation: CompileRules, which reports every bad cell with its path
+// - evaluation: (*CompiledRules).Evaluate
+//
+// A table has input columns, each an expression over the run context, and
+// ordered rules. A rule has one condition cell per column and the outputs it
+// produces when all its cells match. Cells are written for business users:
+//
+//	"" or "-"          matches anything (so does a missing cell)
+//	100, true, "gold"  equals the value ("gold" may be written without quotes;
+//	                   a bare word, even vars or trigger, is always text)
+//	">= 100", "!= 0"   compares the value (<, <=, >, >=, ==, !=)
+//	"in [1, 2]"        the value is one of the list (also a JSON list cell)
+//	"= value % 2 == 0" any expression; "value" is the column's value
+
+// Hit policies.
+const (
+	HitFirst   = "first"   // the first matching rule wins
+	HitCollect = "collect" // every matching rule, in table order
+)
+
+// RuleColumn is an input column of a decision table.
+type RuleColumn struct {
+	Name string
+	Expr string
+}
+
+// Rule is a row: When holds the condition cells keyed by column name, Then
+// the outputs.
+type Rule struct {
+	When map[string]interface{}
+	Then map[string]interface{}
+}
+
+// RuleTable is a decision table as stored in node data.
+type RuleTable struct {
+	HitPolicy string
+	Columns   []RuleColumn
+	Rules     []Rule
+}
+
+// RuleError locates a problem in a table, e.g. rules[2].when.amount.
+type RuleError struct {
+	Field string
+	Err   error
+}
+
+func (e *RuleError) Error() string { return e.Field + ": " + e.Err.Error() }
+
+func (e *RuleError) Unwrap() error { return e.Err }
+
+// CompiledRules is a checked table, safe for concurrent use.
+type CompiledRules struct {
+	hitPolicy string
+	columns   []RuleColumn
+	inputs    []*expr.Program
+	// cells[rule][column] is nil for cells that match anything.
+	cells [][]*expr.Program
+}
+
+// CompileRules checks a table. Column expressions may use names; cells may
+// also use "value". All problems are returned, not just the first.
+func CompileRules(t RuleTable, names ...string) (*CompiledRules, []*RuleError) {
+	var errs []*RuleError
+	fail := func(field string, err error) {
+		errs = append(errs, &RuleError{Field: field, Err: err})
+	}
+
+	c := &CompiledRules{hitPolicy: t.HitPolicy, columns: t.Columns}
+	switch t.HitPolicy {
+	case "":
+		c.hitPolicy = HitFirst
+	case HitFirst, HitCollect:
+	default:
+		fail("hitPolicy", fmt.Errorf("unknown hit policy %q (first, collect)", t.HitPolicy))
+	}
+
+	if len(t.Columns) == 0 {
+		fail("columns", errors.New("a decision table needs at least one column"))
+	}
+	index := map[string]int{}
+	for i, col := range t.Columns {
+		field := fmt.Sprintf("columns[%d]", i)
+		if col.Name == "" {
+			fail(field+".name", errors.New("is required"))
+		} else if _, dup := index[col.Name]; dup {
+			fail(field+".name", fmt.Errorf("duplicate column %q", col.Name))
+		}
+		index[col.Name] = i
+
+		prog, err := expr.Compile(col.Expr, names...)
+		if err != nil {
+			fail(field+".expr", err)
+		}
+		c.inputs = append(c.inputs, prog)
+	}
+
+	cellNames := append(append([]string(nil), names...), "value")
+	for r, rule := range t.Rules {
+		row := make([]*expr.Program, len(t.Columns))
+		cols := make([]string, 0, len(rule.When))
+		for name := range rule.When {
+			cols = append(cols, name)
+		}
+		sort.Strings(cols)
+		for _, name := range cols {
+			cell := rule.When[name]
+			field := fmt.Sprintf("rules[%d].when.%s", r, name)
+			i, ok := index[name]
+			if !ok {
+				fail(field, errors.New("no such column"))
+				continue
+			}
+			prog, err := compileCell(cell, cellNames)
+			if err != nil {
+				fail(field, err)
+				continue
+			}
+			row[i] = prog
+		}
+		c.cells = append(c.cells, row)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return c, nil
+}
+
+// HitPolicy returns the table's hit policy.
+func (c *CompiledRules) HitPolicy() string { return c.hitPolicy }
+
+// Evaluate runs the table against env and returns the indexes of the
+// matching rules: at most one under HitFirst.
+func (c *CompiledRules) Evaluate(env map[string]interface{}) ([]int, error) {
+	values := make([]interface{}, len(c.inputs))
+	for i, prog := range c.inputs {
+		v, err := prog.Eval(env)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", c.columns[i].Name, err)
+		}
+		values[i] = v
+	}
+
+	// One scope per column, with "value" bound to the column's value.
+	scopes := make([]map[string]interface{}, len(values))
+	for i, v := range values {
+		scopes[i] = make(map[string]interface{}, len(env)+1)
+		for k, e := range env {
+			scopes[i][k] = e
+		}
+		scopes[i]["value"] = v
+	}
+
+	matched := []int{}
+	for r, row := range c.cells {
+		ok := true
+		for i, cell := range row {
+			if cell == nil {
+				continue
+			}
+			hit, err := cell.EvalBool(scopes[i])
+			if err != nil {
+				return nil, fmt.Errorf("rule %d, column %s: %w", r, c.columns[i].Name, err)
+			}
+			if !hit {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		matched = append(matched, r)
+		if c.hitPolicy == HitFirst {
+			break
+		}
+	}
+	return matched, nil
+}
+
+// cellOperators are the prefixes that compare the column value.
+var cellOperators = []string{"<=", ">=", "==", "!=", "<", ">", "in "}
+
+func hasCellOperator(s string) bool {
+	for _, op := range cellOperators {
+		if strings.HasPrefix(s, op) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileCell turns a cell into a boolean expression over "value", or nil
+// for a cell that matches anything.
+func compileCell(cell interface{}, names []string) (*expr.Program, error) {
+	s, isString := cell.(string)
+	if !isString {
+		if cell == nil {
+			return nil, nil
+		}
+		lit, err := literalSrc(cell)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(lit, "[") {
+			return expr.Compile("value in "+lit, names...)
+		}
+		return expr.Compile("value == "+lit, names...)
+	}
+
+	s = strings.TrimSpace(s)
+	if s == "" || s == "-" {
+		return nil, nil
+	}
+	var src string
+	switch {
+	case strings.HasPrefix(s, "=") && !strings.HasPrefix(s, "=="):
+		src = s[1:]
+	case s == "value":
+		return nil, errors.New(`"value" always matches; use "-" to match anything or "= value" for a truthy value`)
+	case isName(s, names):
+		// A bare word such as vars or trigger is text, not the context
+		// variable; write "= vars" for the variable.
+		return expr.Compile("value == "+quote(s), names...)
+	case hasCellOperator(s):
+		src = "value " + s
+	default:
+		src = "value == (" + s + ")"
+	}
+
+	prog, err := expr.Compile(src, names...)
+	if err != nil && !strings.ContainsAny(s, `()[]<>=!&|"'`) {
+		// Bare text such as gold, New York or "in progress": compare as text.
+		return expr.Compile("value == "+quote(s), names...)
+	}
+	return prog, err
+}
+
+func isName(s string, names []string) bool {
+	for _, n := range names {
+		if s == n {
+			return true
+		}
+	}
+	return false
+}
+
+// literalSrc renders a JSON cell value as an expression literal.
+func literalSrc(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return quote(t), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(t), nil
+	case int32:
+		return strconv.FormatInt(int64(t), 10), nil
+	case int64:
+		return strconv.FormatInt(t, 10), nil
+	case []interface{}:
+		parts := make([]string, len(t))
+		for i, e := range t {
+			p, err := literalSrc(e)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = p
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	}
+	return "", fmt.Errorf("unsupported cell value %v", v)
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(s) + `"`
+}
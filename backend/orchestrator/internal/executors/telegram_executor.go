@@ -1 +1,269 @@
-package executors
\ No newline at end of file
+package executors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// This file provides the Telegram channel, built like the WhatsApp one:
+// - webhook handler: HandleTelegramWebhook
+// - Waiter registration: WaitForTelegramMessage
+// - Send helper: SendTelegramMessage
+//
+// Configuration (env):
+//   TELEGRAM_BOT_TOKEN       bot token from @BotFather
+//   TELEGRAM_API_BASE        Bot API base URL, default https://api.telegram.org;
+//                            point it at a fake server in tests
+//   TELEGRAM_WEBHOOK_SECRET  optional; when set, updates must carry it in the
+//                            X-Telegram-Bot-Api-Secret-Token header (pass it
+//                            as secret_token to setWebhook)
+
+const defaultTelegramAPIBase = "https://api.telegram.org"
+
+// TelegramMessage is an incoming message as delivered to a waiting node.
+type TelegramMessage struct {
+	ChatID    string `json:"chatId"`
+	MessageID int64  `json:"messageId"`
+	From      string `json:"from"`
+	Text      string `json:"text"`
+	Date      int64  `json:"date"`
+}
+
+// telegramWaiter is a wait node blocked on a message. An empty chatID
+// accepts a message from any chat.
+type telegramWaiter struct {
+	chatID string
+	seq    uint64
+	ch     chan TelegramMessage
+}
+
+// In-memory waiter registry: key = runID + ":" + nodeID. Separate from the
+// WhatsApp one so a message never crosses channels.
+var telegramWaiters = struct {
+	mu  sync.Mutex
+	m   map[string]*telegramWaiter
+	seq uint64
+}{m: map[string]*telegramWaiter{}}
+
+// registerTelegramWaiter registers a waiter for a message from chatID.
+func registerTelegramWaiter(runID, nodeID, chatID string) chan TelegramMessage {
+	telegramWaiters.mu.Lock()
+	defer telegramWaiters.mu.Unlock()
+	telegramWaiters.seq++
+	w := &telegramWaiter{chatID: chatID, seq: telegramWaiters.seq, ch: make(chan TelegramMessage, 1)}
+	telegramWaiters.m[waiterKey(runID, nodeID)] = w
+	return w.ch
+}
+
+// deliverTelegramMessage hands msg to the longest-waiting node listening on
+// its chat. It reports whether one was found.
+func deliverTelegramMessage(msg TelegramMessage) bool {
+	telegramWaiters.mu.Lock()
+	defer telegramWaiters.mu.Unlock()
+
+	var key string
+	var first *telegramWaiter
+	for k, w := range telegramWaiters.m {
+		if w.chatID != "" && w.chatID != msg.ChatID {
+			continue
+		}
+		if first == nil || w.seq < first.seq {
+			key, first = k, w
+		}
+	}
+	if first == nil {
+		return false
+	}
+	first.ch <- msg
+	delete(telegramWaiters.m, key)
+	return true
+}
+
+// cancelTelegramWaiters removes the Telegram waiters of runID; see
+// CancelWaiters.
+func cancelTelegramWaiters(runID string) int {
+	prefix := runID + ":"
+	telegramWaiters.mu.Lock()
+	defer telegramWaiters.mu.Unlock()
+	n := 0
+	for k, w := range telegramWaiters.m {
+		if strings.HasPrefix(k, prefix) {
+			close(w.ch)
+			delete(telegramWaiters.m, k)
+			n++
+		}
+	}
+	return n
+}
+
+// WaitForTelegramMessage blocks until a message from chatID ("" = any chat)
+// arrives or ctx is done, in which case the waiter is removed so a late
+// message goes to the next waiter instead.
+func WaitForTelegramMessage(ctx context.Context, runID, nodeID, chatID string) (TelegramMessage, error) {
+	ch := registerTelegramWaiter(runID, nodeID, chatID)
+	select {
+	case msg, ok := <-ch:
+		if !ok {
+			return TelegramMessage{}, context.Canceled
+		}
+		return msg, nil
+	case <-ctx.Done():
+		telegramWaiters.mu.Lock()
+		if w, ok := telegramWaiters.m[waiterKey(runID, nodeID)]; ok && w.ch == ch {
+			delete(telegramWaiters.m, waiterKey(runID, nodeID))
+		}
+		telegramWaiters.mu.Unlock()
+		return TelegramMessage{}, ctx.Err()
+	}
+}
+
+// telegramUpdate is the part of a Bot API Update we use.
+type telegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		MessageID int64  `json:"message_id"`
+		Date      int64  `json:"date"`
+		Text      string `json:"text"`
+		Caption   string `json:"caption"`
+		Chat      struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		From *struct {
+			ID        int64  `json:"id"`
+			Username  string `json:"username"`
+			FirstName string `json:"first_name"`
+		} `json:"from"`
+	} `json:"message"`
+}
+
+// HandleTelegramWebhook is the HTTP handler for Bot API updates.
+// Mount as "/webhook/telegram".
+func HandleTelegramWebhook(w http.ResponseWriter, r *http.Request) {
+	if secret := os.Getenv("TELEGRAM_WEBHOOK_SECRET"); secret != "" &&
+		r.Header.Get("X-Telegram-Bot-Api-Secret-Token") != secret {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var update telegramUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		log.Println("telegram update decode err:", err)
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	// Telegram re-sends an update until it gets a 2xx, so everything that
+	// is not a deliverable message is acknowledged and dropped.
+	if update.Message == nil {
+		io.WriteString(w, "Ignored")
+		return
+	}
+
+	m := update.Message
+	msg := TelegramMessage{
+		ChatID:    strconv.FormatInt(m.Chat.ID, 10),
+		MessageID: m.MessageID,
+		Text:      m.Text,
+		Date:      m.Date,
+	}
+	if msg.Text == "" {
+		msg.Text = m.Caption
+	}
+	if m.From != nil {
+		msg.From = m.From.Username
+		if msg.From == "" {
+			msg.From = m.From.FirstName
+		}
+	}
+	log.Printf("Telegram incoming chat=%s from=%s text=%s\n", msg.ChatID, msg.From, msg.Text)
+
+	if deliverTelegramMessage(msg) {
+		io.WriteString(w, "Delivered")
+		return
+	}
+	io.WriteString(w, "No registered waiter")
+}
+
+// telegramAPIURL returns the URL of a Bot API method.
+func telegramAPIURL(method string) (string, error) {
+	token := os.Getenv("TELEGRAM_BOT_TOKEN")
+	if token == "" {
+		return "", errors.New("telegram env vars not set")
+	}
+	base := strings.TrimRight(os.Getenv("TELEGRAM_API_BASE"), "/")
+	if base == "" {
+		base = defaultTelegramAPIBase
+	}
+	return fmt.Sprintf("%s/bot%s/%s", base, token, method), nil
+}
+
+// SendTelegramMessage sends text to a chat (a numeric ID or "@channel").
+// parseMode is "", "HTML", "Markdown" or "MarkdownV2". It returns the ID of
+// the sent message.
+func SendTelegramMessage(ctx context.Context, chatID, text, parseMode string) (int64, error) {
+	endpoint, err := telegramAPIURL("sendMessage")
+	if err != nil {
+		return 0, err
+	}
+
+	payload := map[string]interface{}{"chat_id": chatID, "text": text}
+	if parseMode != "" {
+		payload["parse_mode"] = parseMode
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL holds the bot token.
+		return 0, errors.New(strings.ReplaceAll(err.Error(), os.Getenv("TELEGRAM_BOT_TOKEN"), "<token>"))
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, NewHTTPError("telegram", resp.StatusCode, string(b))
+	}
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+		Result      struct {
+			MessageID int64 `json:"message_id"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(b, &result); err != nil {
+		return 0, fmt.Errorf("telegram response: %w", err)
+	}
+	if !result.OK {
+		return 0, fmt.Errorf("telegram error: %s", result.Description)
+	}
+	log.Printf("Telegram send ok. message_id=%d\n", result.Result.MessageID)
+	return result.Result.MessageID, nil
+}
+
+// Gin wrapper for webhook handler
+func HandleTelegramWebhookGin(c *gin.Context) {
+	HandleTelegramWebhook(c.Writer, c.Request)
+}
@@ -1,6 +1,7 @@
 package executors
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -75,6 +76,28 @@ func deliverMessageToWaiter(runID, nodeID, text string) bool {
 	return false
 }
 
+// CancelWaiters removes every waiter registered for runID, WhatsApp and
+// Telegram, and wakes it up so the waiting node returns right away. It
+// returns how many were removed.
+func CancelWaiters(runID string) int {
+	prefix := runID + ":"
+	n := cancelTelegramWaiters(runID)
+	waiters.m.Range(func(k, v interface{}) bool {
+		if key, ok := k.(string); ok && strings.HasPrefix(key, prefix) {
+			if ch, ok := v.(chan string); ok {
+				select {
+				case ch <- "__CANCELLED__":
+				default:
+				}
+			}
+			waiters.m.Delete(k)
+			n++
+		}
+		return true
+	})
+	return n
+}
+
 // Webhook payload handling (Twilio sends form values)
 type twilioWebhookPayload struct {
 	From string
@@ -212,19 +235,28 @@ func HandleWhatsAppWebhook(w http.ResponseWriter, r *http.Request) {
 }
 
 // WaitNode execution: called by orchestrator when a Wait node runs.
-// It registers a waiter and blocks until a message arrives.
+// It registers a waiter and blocks until a message arrives or ctx is done,
+// in which case the waiter is removed so a late reply is not swallowed.
 // Return: incoming text or error.
-func WaitForWhatsAppMessage(runID, nodeID string, timeoutSeconds int) (string, error) {
-	ch, err := RegisterWaiter(runID, nodeID, timeoutSeconds)
+func WaitForWhatsAppMessage(ctx context.Context, runID, nodeID string) (string, error) {
+	ch, err := RegisterWaiter(runID, nodeID, 0)
 	if err != nil {
 		return "", err
 	}
 	// Wait for message
-	msg := <-ch
-	if msg == "__TIMEOUT__" {
-		return "", errors.New("waiter timeout")
+	select {
+	case msg := <-ch:
+		if msg == "__TIMEOUT__" {
+			return "", errors.New("waiter timeout")
+		}
+		if msg == "__CANCELLED__" {
+			return "", context.Canceled
+		}
+		return msg, nil
+	case <-ctx.Done():
+		waiters.m.Delete(waiterKey(runID, nodeID))
+		return "", ctx.Err()
 	}
-	return msg, nil
 }
 
 // Static template with regex captures.
@@ -259,7 +291,7 @@ func BuildStaticReply(regexPattern, template, message string) (string, error) {
 }
 
 // SendWhatsAppMessage posts to Twilio to send WhatsApp message.
-func SendWhatsAppMessage(to, body string) error {
+func SendWhatsAppMessage(ctx context.Context, to, body string) error {
 	accountSid := os.Getenv("TWILIO_SID")
 	authToken := os.Getenv("TWILIO_AUTH_TOKEN")
 	from := os.Getenv("TWILIO_WHATSAPP_FROM") // e.g. whatsapp:+1415...
@@ -276,7 +308,7 @@ func SendWhatsAppMessage(to, body string) error {
 	data.Set("From", from)
 	data.Set("Body", body)
 
-	req, err := http.NewRequest("POST", endpoint, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(data.Encode()))
 	if err != nil {
 		return err
 	}
@@ -294,13 +326,13 @@ func SendWhatsAppMessage(to, body string) error {
 		log.Printf("Twilio send ok. resp=%s\n", string(b))
 		return nil
 	}
-	return fmt.Errorf("twilio error status=%d body=%s", resp.StatusCode, string(b))
+	return NewHTTPError("twilio", resp.StatusCode, string(b))
 }
 
 // Example orchestrator-facing helper: ExecuteWhatsAppSendNode
 // `input` is the incoming message (if any) or previous node output.
 // mode: "static" or "ai". For "static" regexPattern + template used. For "ai", call internal ai.
-func ExecuteWhatsAppSendNode(to string, mode string, regexPattern, template, input string) (string, error) {
+func ExecuteWhatsAppSendNode(ctx context.Context, to string, mode string, regexPattern, template, input string) (string, error) {
 	out := ""
 	if mode == "static" {
 		r, err := BuildStaticReply(regexPattern, template, input)
@@ -322,7 +354,7 @@ func ExecuteWhatsAppSendNode(to string, mode string, regexPattern, template, inp
 	}
 
 	// send out
-	if err := SendWhatsAppMessage(to, out); err != nil {
+	if err := SendWhatsAppMessage(ctx, to, out); err != nil {
 		return "", err
 	}
 	return out, nil
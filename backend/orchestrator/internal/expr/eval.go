@@ -0,0 +1,478 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type node interface {
+	eval(s *scope) (interface{}, error)
+}
+
+type literal struct{ v interface{} }
+
+type ident struct{ name string }
+
+// member is x.key or x[key].
+type member struct {
+	x   node
+	key node
+}
+
+type listLit struct{ items []node }
+
+type unary struct {
+	op string
+	x  node
+}
+
+type binary struct {
+	op   string
+	l, r node
+}
+
+type call struct {
+	name string
+	fn   *function
+	args []node
+	re   *regexp.Regexp // the compiled regex argument, if it is a literal
+}
+
+// iteration is map(list, expr) or filter(list, expr): expr is evaluated for
+// every item of the list with the item bound to "item" and its position to
+// "index".
+type iteration struct {
+	name       string
+	list, body node
+}
+
+func (n *literal) eval(s *scope) (interface{}, error) {
+	return n.v, s.step()
+}
+
+func (n *ident) eval(s *scope) (interface{}, error) {
+	if err := s.step(); err != nil {
+		return nil, err
+	}
+	v, ok := s.env[n.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown name %q", n.name)
+	}
+	return normalize(v), nil
+}
+
+// member access never fails on missing data: a missing key, an index out of
+// range or access into null all yield null.
+func (n *member) eval(s *scope) (interface{}, error) {
+	x, err := n.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	key, err := n.key.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := x.(type) {
+	case map[string]interface{}:
+		k, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("object key must be a string, got %s", typeName(key))
+		}
+		return normalize(t[k]), nil
+	case []interface{}:
+		f, ok := key.(float64)
+		if !ok {
+			return nil, fmt.Errorf("list index must be a number, got %s", typeName(key))
+		}
+		i := int(f)
+		if i < 0 {
+			i += len(t)
+		}
+		if i < 0 || i >= len(t) {
+			return nil, nil
+		}
+		return normalize(t[i]), nil
+	case nil:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("cannot access %v of %s", key, typeName(x))
+}
+
+func (n *listLit) eval(s *scope) (interface{}, error) {
+	out := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		v, err := item.eval(s)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func (n *unary) eval(s *scope) (interface{}, error) {
+	x, err := n.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		return !truthy(x), nil
+	}
+	f, ok := x.(float64)
+	if !ok {
+		return nil, fmt.Errorf("cannot negate %s", typeName(x))
+	}
+	return -f, nil
+}
+
+func (n *binary) eval(s *scope) (interface{}, error) {
+	l, err := n.l.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit.
+	switch n.op {
+	case "&&":
+		if !truthy(l) {
+			return false, nil
+		}
+		r, err := n.r.eval(s)
+		return truthy(r), err
+	case "||":
+		if truthy(l) {
+			return true, nil
+		}
+		r, err := n.r.eval(s)
+		return truthy(r), err
+	}
+
+	r, err := n.r.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	case "<", "<=", ">", ">=":
+		return compare(n.op, l, r)
+	case "in":
+		return memberOf(l, r)
+	case "+":
+		_, lok := l.(string)
+		_, rok := r.(string)
+		if lok || rok {
+			ls, rs := toString(l), toString(r)
+			if err := s.chargeString(len(ls) + len(rs)); err != nil {
+				return nil, err
+			}
+			return ls + rs, nil
+		}
+	}
+
+	lf, lok := l.(float64)
+	rf, rok := r.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("operator %s needs numbers, got %s and %s", n.op, typeName(l), typeName(r))
+	}
+	switch n.op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return lf / rf, nil
+	case "%":
+		if rf == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return math.Mod(lf, rf), nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", n.op)
+}
+
+func (n *call) eval(s *scope) (interface{}, error) {
+	if err := s.step(); err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(s)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	if n.re != nil {
+		args[n.fn.regexArg-1] = n.re
+	}
+	v, err := n.fn.call(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %w", n.name, err)
+	}
+	if str, ok := v.(string); ok {
+		if err := s.chargeString(len(str)); err != nil {
+			return nil, fmt.Errorf("%s(): %w", n.name, err)
+		}
+	}
+	return v, nil
+}
+
+func (n *iteration) eval(s *scope) (interface{}, error) {
+	if err := s.step(); err != nil {
+		return nil, err
+	}
+	x, err := n.list.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	if x == nil {
+		return []interface{}{}, nil
+	}
+	list, ok := x.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s(): first argument must be a list, got %s", n.name, typeName(x))
+	}
+
+	// The body sees the enclosing names plus item and index; nested
+	// iterations shadow them and put them back when done.
+	outer := s.env
+	env := make(map[string]interface{}, len(outer)+2)
+	for k, v := range outer {
+		env[k] = v
+	}
+	s.env = env
+	defer func() { s.env = outer }()
+
+	out := make([]interface{}, 0, len(list))
+	for i, item := range list {
+		item = normalize(item)
+		env["item"] = item
+		env["index"] = float64(i)
+		v, err := n.body.eval(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s(): item %d: %w", n.name, i, err)
+		}
+		switch {
+		case n.name == "map":
+			out = append(out, v)
+		case truthy(v):
+			out = append(out, item)
+		}
+	}
+	return out, nil
+}
+
+// normalize maps the shapes run data comes in (ints, Mongo documents and
+// arrays) onto the expression types: float64, string, bool, nil, time.Time,
+// []interface{} and map[string]interface{}.
+func normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case int:
+		return float64(t)
+	case int32:
+		return float64(t)
+	case int64:
+		return float64(t)
+	case float32:
+		return float64(t)
+	case primitive.M:
+		return map[string]interface{}(t)
+	case primitive.A:
+		return []interface{}(t)
+	case []string:
+		out := make([]interface{}, len(t))
+		for i, s := range t {
+			out[i] = s
+		}
+		return out
+	case primitive.DateTime:
+		return t.Time()
+	case primitive.D:
+		m := make(map[string]interface{}, len(t))
+		for _, e := range t {
+			m[e.Key] = e.Value
+		}
+		return m
+	}
+	return v
+}
+
+func truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case float64:
+		return t != 0
+	case string:
+		return t != ""
+	case []interface{}:
+		return len(t) > 0
+	case map[string]interface{}:
+		return len(t) > 0
+	}
+	return true
+}
+
+func equal(l, r interface{}) bool {
+	if lt, ok := l.(time.Time); ok {
+		if rt, ok := asTime(r); ok {
+			return lt.Equal(rt)
+		}
+		return false
+	}
+	if rt, ok := r.(time.Time); ok {
+		if lt, ok := asTime(l); ok {
+			return lt.Equal(rt)
+		}
+		return false
+	}
+	return reflect.DeepEqual(l, r)
+}
+
+// compare orders two numbers, two strings or two dates. A date compared with
+// a string parses the string. Ordering against null is false.
+func compare(op string, l, r interface{}) (bool, error) {
+	if l == nil || r == nil {
+		return false, nil
+	}
+
+	var c int
+	switch lv := l.(type) {
+	case float64:
+		rv, ok := r.(float64)
+		if !ok {
+			return false, fmt.Errorf("cannot compare number with %s", typeName(r))
+		}
+		c = cmpFloat(lv, rv)
+	case string:
+		if rt, ok := r.(time.Time); ok {
+			lt, ok := asTime(lv)
+			if !ok {
+				return false, fmt.Errorf("cannot compare %q with a date", lv)
+			}
+			c = cmpTime(lt, rt)
+			break
+		}
+		rv, ok := r.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot compare string with %s", typeName(r))
+		}
+		c = strings.Compare(lv, rv)
+	case time.Time:
+		rt, ok := asTime(r)
+		if !ok {
+			return false, fmt.Errorf("cannot compare date with %s", typeName(r))
+		}
+		c = cmpTime(lv, rt)
+	default:
+		return false, fmt.Errorf("cannot order %s", typeName(l))
+	}
+
+	switch op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+// memberOf implements "x in y".
+func memberOf(x, in interface{}) (bool, error) {
+	switch t := in.(type) {
+	case []interface{}:
+		for _, e := range t {
+			if equal(x, normalize(e)) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case string:
+		return strings.Contains(t, toString(x)), nil
+	case map[string]interface{}:
+		k, ok := x.(string)
+		if !ok {
+			return false, nil
+		}
+		_, found := t[k]
+		return found, nil
+	case nil:
+		return false, nil
+	}
+	return false, fmt.Errorf("cannot use in with %s", typeName(in))
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func cmpTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+func toString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case time.Time:
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case time.Time:
+		return "date"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,140 @@
+// Package expr is the small, sandboxed expression language used by decision
+// and loop conditions and by transform mappings:
+//
+//	len(nodes.wait1.input) > 0 && contains(lower(nodes.wait1.input), "yes")
+//
+// Expressions read the run context (nodes, trigger, vars...) and call a fixed
+// set of functions; they cannot reach anything else. Evaluation is bounded
+// by a step budget, regular expressions are RE2 (linear time).
+//
+// Syntax:
+//
+//	literals     1, 2.5, "text", 'text', true, false, null, [1, 2]
+//	access       nodes.ai1.output, trigger.body["first-name"], list[0]
+//	arithmetic   + - * / %   (+ also joins strings)
+//	comparison   == != < <= > >=   (numbers, strings and dates)
+//	membership   x in list, "sub" in text, "key" in object
+//	logic        && || !   (or: and, or, not)
+//	lists        map(list, item.name), filter(list, item.age > 18)
+//
+// See funcs.go for the functions.
+package expr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxLength bounds the source length of an expression.
+const MaxLength = 4096
+
+// maxSteps bounds the work a single evaluation may do.
+const maxSteps = 10000
+
+// MaxStringLength bounds the length of any string an expression builds.
+const MaxStringLength = 1 << 20
+
+// stringStepBytes is how many bytes of a built string cost one step.
+const stringStepBytes = 256
+
+// Program is a compiled expression. It is safe for concurrent use.
+type Program struct {
+	src  string
+	root node
+}
+
+// Compile parses src and checks every function call. If names are given,
+// they are the only top-level identifiers the expression may use.
+func Compile(src string, names ...string) (*Program, error) {
+	if len(src) > MaxLength {
+		return nil, fmt.Errorf("expression longer than %d characters", MaxLength)
+	}
+
+	p := &parser{lex: newLexer(src)}
+	if len(names) > 0 {
+		p.names = map[string]bool{}
+		for _, n := range names {
+			p.names[n] = true
+		}
+	}
+
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Program{src: src, root: root}, nil
+}
+
+// String returns the source of the expression.
+func (p *Program) String() string { return p.src }
+
+// Eval evaluates the program against env, whose keys are the top-level
+// identifiers.
+func (p *Program) Eval(env map[string]interface{}) (interface{}, error) {
+	s := &scope{env: env}
+	v, err := p.root.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// EvalBool evaluates the program and reports whether the result is truthy:
+// not null, false, 0, "" or an empty list/object.
+func (p *Program) EvalBool(env map[string]interface{}) (bool, error) {
+	v, err := p.Eval(env)
+	if err != nil {
+		return false, err
+	}
+	return truthy(v), nil
+}
+
+// Eval compiles and evaluates src in one go.
+func Eval(src string, env map[string]interface{}) (interface{}, error) {
+	p, err := Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return p.Eval(env)
+}
+
+// SyntaxError reports where an expression could not be parsed.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at %d: %s", e.Pos, e.Msg)
+}
+
+var errBudget = errors.New("expression too expensive to evaluate")
+
+var errStringTooLong = fmt.Errorf("string longer than %d bytes", MaxStringLength)
+
+// scope carries the environment and the remaining step budget of one
+// evaluation.
+type scope struct {
+	env   map[string]interface{}
+	steps int
+}
+
+func (s *scope) step() error {
+	s.steps++
+	if s.steps > maxSteps {
+		return errBudget
+	}
+	return nil
+}
+
+// chargeString accounts for building a string of n bytes.
+func (s *scope) chargeString(n int) error {
+	if n > MaxStringLength {
+		return errStringTooLong
+	}
+	s.steps += n / stringStepBytes
+	if s.steps > maxSteps {
+		return errBudget
+	}
+	return nil
+}
@@ -0,0 +1,426 @@
+package expr
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// function is a built-in callable from expressions. maxArgs < 0 means any
+// number of arguments; regexArg is the 1-based position of a regex pattern
+// argument, checked at compile time when it is a literal. Functions with
+// iterates set take a list and an expression evaluated once per item (see
+// iteration) instead of being called with evaluated arguments.
+type function struct {
+	minArgs, maxArgs int
+	regexArg         int
+	iterates         bool
+	call             func(args []interface{}) (interface{}, error)
+}
+
+func (f *function) arity() string {
+	switch {
+	case f.maxArgs < 0:
+		return fmt.Sprintf("at least %d argument(s)", f.minArgs)
+	case f.minArgs == f.maxArgs:
+		return fmt.Sprintf("%d argument(s)", f.minArgs)
+	}
+	return fmt.Sprintf("%d to %d arguments", f.minArgs, f.maxArgs)
+}
+
+// functions available to expressions.
+//
+// Strings:  len lower upper trim contains startsWith endsWith split join
+//
+//	replace substr matches find
+//
+// Lists:    map filter keys
+// Values:   string number bool default
+// Numbers:  abs round floor ceil min max
+// Dates:    now date formatDate addDuration addDays diffSeconds year month
+//
+//	day hour weekday
+var functions map[string]*function
+
+func init() {
+	functions = map[string]*function{
+		"len": {minArgs: 1, maxArgs: 1, call: func(a []interface{}) (interface{}, error) {
+			switch t := a[0].(type) {
+			case nil:
+				return 0.0, nil
+			case string:
+				return float64(len([]rune(t))), nil
+			case []interface{}:
+				return float64(len(t)), nil
+			case map[string]interface{}:
+				return float64(len(t)), nil
+			}
+			return nil, fmt.Errorf("no length for %s", typeName(a[0]))
+		}},
+		"lower": strFunc(strings.ToLower),
+		"upper": strFunc(strings.ToUpper),
+		"trim":  strFunc(strings.TrimSpace),
+		"contains": {minArgs: 2, maxArgs: 2, call: func(a []interface{}) (interface{}, error) {
+			return memberOf(a[1], a[0])
+		}},
+		"startsWith": {minArgs: 2, maxArgs: 2, call: func(a []interface{}) (interface{}, error) {
+			return strings.HasPrefix(toString(a[0]), toString(a[1])), nil
+		}},
+		"endsWith": {minArgs: 2, maxArgs: 2, call: func(a []interface{}) (interface{}, error) {
+			return strings.HasSuffix(toString(a[0]), toString(a[1])), nil
+		}},
+		"split": {minArgs: 2, maxArgs: 2, call: func(a []interface{}) (interface{}, error) {
+			parts := strings.Split(toString(a[0]), toString(a[1]))
+			out := make([]interface{}, len(parts))
+			for i, p := range parts {
+				out[i] = p
+			}
+			return out, nil
+		}},
+		"join": {minArgs: 2, maxArgs: 2, call: func(a []interface{}) (interface{}, error) {
+			list, ok := a[0].([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("first argument must be a list, got %s", typeName(a[0]))
+			}
+			sep := toString(a[1])
+			parts := make([]string, len(list))
+			n := len(sep) * len(list)
+			for i, e := range list {
+				parts[i] = toString(normalize(e))
+				n += len(parts[i])
+			}
+			if n > MaxStringLength {
+				return nil, errStringTooLong
+			}
+			return strings.Join(parts, sep), nil
+		}},
+		"replace": {minArgs: 3, maxArgs: 3, call: func(a []interface{}) (interface{}, error) {
+			str, old, repl := toString(a[0]), toString(a[1]), toString(a[2])
+			// Check the result's length before building it.
+			count := strings.Count(str, old)
+			if len(str)+count*(len(repl)-len(old)) > MaxStringLength {
+				return nil, errStringTooLong
+			}
+			return strings.ReplaceAll(str, old, repl), nil
+		}},
+		"substr": {minArgs: 2, maxArgs: 3, call: func(a []interface{}) (interface{}, error) {
+			r := []rune(toString(a[0]))
+			start, ok := a[1].(float64)
+			if !ok {
+				return nil, errors.New("start must be a number")
+			}
+			from := clamp(int(start), len(r))
+			to := len(r)
+			if len(a) == 3 {
+				n, ok := a[2].(float64)
+				if !ok {
+					return nil, errors.New("length must be a number")
+				}
+				if n < 0 {
+					return nil, errors.New("length must not be negative")
+				}
+				// Huge lengths may overflow int; keep to within [from, len(r)].
+				if to = clamp(from+int(n), len(r)); to < from {
+					to = len(r)
+				}
+			}
+			return string(r[from:to]), nil
+		}},
+		"matches": {minArgs: 2, maxArgs: 2, regexArg: 2, call: func(a []interface{}) (interface{}, error) {
+			re, err := regexArg(a[1])
+			if err != nil {
+				return nil, err
+			}
+			return re.MatchString(toString(a[0])), nil
+		}},
+		"find": {minArgs: 2, maxArgs: 2, regexArg: 2, call: func(a []interface{}) (interface{}, error) {
+			re, err := regexArg(a[1])
+			if err != nil {
+				return nil, err
+			}
+			m := re.FindStringSubmatch(toString(a[0]))
+			switch {
+			case m == nil:
+				return nil, nil
+			case len(m) > 1:
+				return m[1], nil
+			}
+			return m[0], nil
+		}},
+
+		"map":    {minArgs: 2, maxArgs: 2, iterates: true},
+		"filter": {minArgs: 2, maxArgs: 2, iterates: true},
+		"keys": {minArgs: 1, maxArgs: 1, call: func(a []interface{}) (interface{}, error) {
+			m, ok := a[0].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("argument must be an object, got %s", typeName(a[0]))
+			}
+			out := make([]interface{}, 0, len(m))
+			for k := range m {
+				out = append(out, k)
+			}
+			sort.Slice(out, func(i, j int) bool { return out[i].(string) < out[j].(string) })
+			return out, nil
+		}},
+
+		"string": {minArgs: 1, maxArgs: 1, call: func(a []interface{}) (interface{}, error) {
+			return toString(a[0]), nil
+		}},
+		"number": {minArgs: 1, maxArgs: 1, call: func(a []interface{}) (interface{}, error) {
+			switch t := a[0].(type) {
+			case float64:
+				return t, nil
+			case bool:
+				if t {
+					return 1.0, nil
+				}
+				return 0.0, nil
+			case string:
+				f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+				if err != nil {
+					return nil, fmt.Errorf("%q is not a number", t)
+				}
+				return f, nil
+			}
+			return nil, fmt.Errorf("cannot convert %s to a number", typeName(a[0]))
+		}},
+		"bool": {minArgs: 1, maxArgs: 1, call: func(a []interface{}) (interface{}, error) {
+			if s, ok := a[0].(string); ok {
+				switch strings.ToLower(strings.TrimSpace(s)) {
+				case "false", "no", "0", "off":
+					return false, nil
+				}
+			}
+			return truthy(a[0]), nil
+		}},
+		"default": {minArgs: 2, maxArgs: 2, call: func(a []interface{}) (interface{}, error) {
+			if a[0] == nil || a[0] == "" {
+				return a[1], nil
+			}
+			return a[0], nil
+		}},
+
+		"abs":   numFunc(math.Abs),
+		"round": numFunc(math.Round),
+		"floor": numFunc(math.Floor),
+		"ceil":  numFunc(math.Ceil),
+		"min":   foldFunc(math.Min),
+		"max":   foldFunc(math.Max),
+
+		"now": {minArgs: 0, maxArgs: 0, call: func(a []interface{}) (interface{}, error) {
+			return time.Now().UTC(), nil
+		}},
+		"date": {minArgs: 1, maxArgs: 2, call: func(a []interface{}) (interface{}, error) {
+			if len(a) == 2 {
+				t, err := time.Parse(toString(a[1]), toString(a[0]))
+				if err != nil {
+					return nil, err
+				}
+				return t, nil
+			}
+			t, ok := asTime(a[0])
+			if !ok {
+				return nil, fmt.Errorf("cannot read %v as a date", a[0])
+			}
+			return t, nil
+		}},
+		"formatDate": {minArgs: 2, maxArgs: 2, call: func(a []interface{}) (interface{}, error) {
+			t, err := timeArg(a[0])
+			if err != nil {
+				return nil, err
+			}
+			return t.Format(toString(a[1])), nil
+		}},
+		"addDuration": {minArgs: 2, maxArgs: 2, call: func(a []interface{}) (interface{}, error) {
+			t, err := timeArg(a[0])
+			if err != nil {
+				return nil, err
+			}
+			d, err := time.ParseDuration(toString(a[1]))
+			if err != nil {
+				return nil, err
+			}
+			return t.Add(d), nil
+		}},
+		"addDays": {minArgs: 2, maxArgs: 2, call: func(a []interface{}) (interface{}, error) {
+			t, err := timeArg(a[0])
+			if err != nil {
+				return nil, err
+			}
+			n, ok := a[1].(float64)
+			if !ok {
+				return nil, errors.New("days must be a number")
+			}
+			return t.AddDate(0, 0, int(n)), nil
+		}},
+		"diffSeconds": {minArgs: 2, maxArgs: 2, call: func(a []interface{}) (interface{}, error) {
+			x, err := timeArg(a[0])
+			if err != nil {
+				return nil, err
+			}
+			y, err := timeArg(a[1])
+			if err != nil {
+				return nil, err
+			}
+			return x.Sub(y).Seconds(), nil
+		}},
+		"year":    datePart(func(t time.Time) int { return t.Year() }),
+		"month":   datePart(func(t time.Time) int { return int(t.Month()) }),
+		"day":     datePart(func(t time.Time) int { return t.Day() }),
+		"hour":    datePart(func(t time.Time) int { return t.Hour() }),
+		"weekday": datePart(func(t time.Time) int { return int(t.Weekday()) }),
+	}
+}
+
+func strFunc(f func(string) string) *function {
+	return &function{minArgs: 1, maxArgs: 1, call: func(a []interface{}) (interface{}, error) {
+		return f(toString(a[0])), nil
+	}}
+}
+
+func numFunc(f func(float64) float64) *function {
+	return &function{minArgs: 1, maxArgs: 1, call: func(a []interface{}) (interface{}, error) {
+		x, ok := a[0].(float64)
+		if !ok {
+			return nil, fmt.Errorf("argument must be a number, got %s", typeName(a[0]))
+		}
+		return f(x), nil
+	}}
+}
+
+func foldFunc(f func(a, b float64) float64) *function {
+	return &function{minArgs: 1, maxArgs: -1, call: func(a []interface{}) (interface{}, error) {
+		// min(list) as well as min(a, b, ...)
+		if list, ok := a[0].([]interface{}); ok && len(a) == 1 {
+			a = list
+		}
+		if len(a) == 0 {
+			return nil, nil
+		}
+		var acc float64
+		for i, v := range a {
+			x, ok := normalize(v).(float64)
+			if !ok {
+				return nil, fmt.Errorf("arguments must be numbers, got %s", typeName(v))
+			}
+			if i == 0 {
+				acc = x
+			} else {
+				acc = f(acc, x)
+			}
+		}
+		return acc, nil
+	}}
+}
+
+func datePart(f func(time.Time) int) *function {
+	return &function{minArgs: 1, maxArgs: 1, call: func(a []interface{}) (interface{}, error) {
+		t, err := timeArg(a[0])
+		if err != nil {
+			return nil, err
+		}
+		return float64(f(t)), nil
+	}}
+}
+
+func timeArg(v interface{}) (time.Time, error) {
+	t, ok := asTime(v)
+	if !ok {
+		return time.Time{}, fmt.Errorf("cannot read %v as a date", v)
+	}
+	return t, nil
+}
+
+// dateLayouts are tried in order when a string is read as a date.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// asTime reads dates: a time, an RFC 3339 / ISO date string or a Unix
+// timestamp in seconds.
+func asTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case float64:
+		sec, frac := math.Modf(t)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), true
+	case string:
+		s := strings.TrimSpace(t)
+		for _, layout := range dateLayouts {
+			if parsed, err := time.Parse(layout, s); err == nil {
+				return parsed, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func clamp(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i > n {
+		return n
+	}
+	return i
+}
+
+// regexCache keeps the compiled regex literals of recently parsed
+// expressions; conditions are compiled for every run, usually with the same
+// few patterns. Patterns built at run time are never cached: they may come
+// from message text and would grow the cache without bound.
+var regexCache = struct {
+	sync.Mutex
+	ll *list.List // front = most recently used
+	m  map[string]*list.Element
+}{ll: list.New(), m: map[string]*list.Element{}}
+
+// maxCachedRegexes bounds regexCache.
+const maxCachedRegexes = 256
+
+type cachedRegex struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// literalRegex compiles a pattern that is a literal in an expression.
+func literalRegex(pattern string) (*regexp.Regexp, error) {
+	regexCache.Lock()
+	defer regexCache.Unlock()
+	if e, ok := regexCache.m[pattern]; ok {
+		regexCache.ll.MoveToFront(e)
+		return e.Value.(*cachedRegex).re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.m[pattern] = regexCache.ll.PushFront(&cachedRegex{pattern: pattern, re: re})
+	if regexCache.ll.Len() > maxCachedRegexes {
+		oldest := regexCache.ll.Back()
+		regexCache.ll.Remove(oldest)
+		delete(regexCache.m, oldest.Value.(*cachedRegex).pattern)
+	}
+	return re, nil
+}
+
+// regexArg returns the regex a function was called with: compiled by the
+// parser for a literal, compiled here otherwise.
+func regexArg(v interface{}) (*regexp.Regexp, error) {
+	if re, ok := v.(*regexp.Regexp); ok {
+		return re, nil
+	}
+	return regexp.Compile(toString(v))
+}
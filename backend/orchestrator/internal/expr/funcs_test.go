@@ -0,0 +1,41 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSubstr(t *testing.T) {
+	for _, tc := range []struct {
+		src  string
+		want string
+		err  string
+	}{
+		{src: `substr("hello", 1)`, want: "ello"},
+		{src: `substr("hello", 1, 3)`, want: "ell"},
+		{src: `substr("héllo", 1, 1)`, want: "é"},
+		{src: `substr("hello", 3, 0)`, want: ""},
+		{src: `substr("hello", -2)`, want: "hello"},
+		{src: `substr("hello", -2, 3)`, want: "hel"},
+		{src: `substr("hello", 9)`, want: ""},
+		{src: `substr("hello", 9, 2)`, want: ""},
+		{src: `substr("hello", 2, 99)`, want: "llo"},
+		{src: `substr("hello", 2, big)`, want: "llo"},
+		{src: `substr("hello", -big, 2)`, want: "he"},
+		{src: `substr("hello", 3, -2)`, err: "length must not be negative"},
+		{src: `substr("hello", 9, -1)`, err: "length must not be negative"},
+		{src: `substr("hello", "1")`, err: "start must be a number"},
+		{src: `substr("hello", 1, "2")`, err: "length must be a number"},
+	} {
+		got, err := Eval(tc.src, map[string]interface{}{"big": 1e300})
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: got %v, %v; want error %q", tc.src, got, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("%s = %q, %v; want %q", tc.src, got, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// parser is a recursive descent parser. Precedence, lowest first:
+//
+//	||  &&  == != < <= > >= in  + -  * / %  ! -(unary)  . [] ()
+type parser struct {
+	lex   *lexer
+	tok   token
+	names map[string]bool // allowed top-level identifiers; nil = any
+}
+
+func (p *parser) parse() (node, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokEOF {
+		return nil, &SyntaxError{Pos: 0, Msg: "empty expression"}
+	}
+
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.unexpected()
+	}
+	return n, nil
+}
+
+func (p *parser) advance() error {
+	t, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = t
+	return nil
+}
+
+// isOp reports whether the current token is one of the given operators or
+// keyword aliases.
+func (p *parser) isOp(ops ...string) (string, bool) {
+	if p.tok.kind != tokOp && p.tok.kind != tokIdent {
+		return "", false
+	}
+	text := p.tok.text
+	if p.tok.kind == tokIdent {
+		switch text {
+		case "and":
+			text = "&&"
+		case "or":
+			text = "||"
+		case "not":
+			text = "!"
+		case "in":
+		default:
+			return "", false
+		}
+	}
+	for _, op := range ops {
+		if text == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(op string) error {
+	if p.tok.kind != tokOp || p.tok.text != op {
+		return &SyntaxError{Pos: p.tok.pos, Msg: fmt.Sprintf("expected %q", op)}
+	}
+	return p.advance()
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokEOF {
+		return &SyntaxError{Pos: p.tok.pos, Msg: "unexpected end of expression"}
+	}
+	return &SyntaxError{Pos: p.tok.pos, Msg: fmt.Sprintf("unexpected %q", p.tok.text)}
+}
+
+// binaryLevel parses one left-associative precedence level.
+func (p *parser) binaryLevel(next func() (node, error), ops ...string) (node, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.isOp(ops...)
+		if !ok {
+			return left, nil
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: op, l: left, r: right}
+	}
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.binaryLevel(p.parseAnd, "||")
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.binaryLevel(p.parseCompare, "&&")
+}
+
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.isOp("==", "!=", "<", "<=", ">", ">=", "in")
+	if !ok {
+		return left, nil
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	right, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	return &binary{op: op, l: left, r: right}, nil
+}
+
+func (p *parser) parseAdd() (node, error) {
+	return p.binaryLevel(p.parseMul, "+", "-")
+}
+
+func (p *parser) parseMul() (node, error) {
+	return p.binaryLevel(p.parseUnary, "*", "/", "%")
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if op, ok := p.isOp("!", "-"); ok {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{op: op, x: x}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok.kind == tokOp {
+		switch p.tok.text {
+		case ".":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if p.tok.kind != tokIdent {
+				return nil, &SyntaxError{Pos: p.tok.pos, Msg: "expected a field name after '.'"}
+			}
+			x = &member{x: x, key: &literal{v: p.tok.text}}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		case "[":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			key, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = &member{x: x, key: key}
+		default:
+			return x, nil
+		}
+	}
+	return x, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.tok
+
+	switch t.kind {
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, &SyntaxError{Pos: t.pos, Msg: "invalid number " + t.text}
+		}
+		return &literal{v: f}, p.advance()
+
+	case tokString:
+		return &literal{v: t.text}, p.advance()
+
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &literal{v: true}, p.advance()
+		case "false":
+			return &literal{v: false}, p.advance()
+		case "null", "nil":
+			return &literal{v: nil}, p.advance()
+		case "and", "or", "not", "in":
+			return nil, p.unexpected()
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokOp && p.tok.text == "(" {
+			return p.parseCall(t)
+		}
+		if p.names != nil && !p.names[t.text] {
+			return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unknown name %q", t.text)}
+		}
+		return &ident{name: t.text}, nil
+
+	case tokOp:
+		switch t.text {
+		case "(":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		case "[":
+			return p.parseList()
+		}
+	}
+	return nil, p.unexpected()
+}
+
+func (p *parser) parseList() (node, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	list := &listLit{}
+	for !(p.tok.kind == tokOp && p.tok.text == "]") {
+		item, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		list.items = append(list.items, item)
+		if p.tok.kind == tokOp && p.tok.text == "," {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !(p.tok.kind == tokOp && p.tok.text == "]") {
+			return nil, p.unexpected()
+		}
+	}
+	return list, p.advance()
+}
+
+// parseCall parses the argument list of name(...) and checks the function
+// exists and takes that many arguments. Constant regex patterns are compiled
+// here so a bad pattern is reported when the workflow is saved.
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, &SyntaxError{Pos: name.pos, Msg: fmt.Sprintf("unknown function %q", name.text)}
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if fn.iterates {
+		return p.parseIteration(name)
+	}
+
+	c := &call{name: name.text, fn: fn}
+	for !(p.tok.kind == tokOp && p.tok.text == ")") {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		c.args = append(c.args, arg)
+		if p.tok.kind == tokOp && p.tok.text == "," {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !(p.tok.kind == tokOp && p.tok.text == ")") {
+			return nil, p.unexpected()
+		}
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if len(c.args) < fn.minArgs || (fn.maxArgs >= 0 && len(c.args) > fn.maxArgs) {
+		return nil, &SyntaxError{Pos: name.pos, Msg: fmt.Sprintf("%s() takes %s", name.text, fn.arity())}
+	}
+	if fn.regexArg > 0 && len(c.args) >= fn.regexArg {
+		if lit, ok := c.args[fn.regexArg-1].(*literal); ok {
+			if s, ok := lit.v.(string); ok {
+				re, err := literalRegex(s)
+				if err != nil {
+					return nil, &SyntaxError{Pos: name.pos, Msg: fmt.Sprintf("%s(): invalid regex: %v", name.text, err)}
+				}
+				c.re = re
+			}
+		}
+	}
+	return c, nil
+}
+
+// parseIteration parses the arguments of map(list, expr) and
+// filter(list, expr). The second argument may also use item and index.
+func (p *parser) parseIteration(name token) (node, error) {
+	list, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(","); err != nil {
+		return nil, &SyntaxError{Pos: name.pos, Msg: fmt.Sprintf("%s() takes a list and an expression", name.text)}
+	}
+
+	if p.names != nil {
+		outer := p.names
+		p.names = make(map[string]bool, len(outer)+2)
+		for k := range outer {
+			p.names[k] = true
+		}
+		p.names["item"] = true
+		p.names["index"] = true
+		defer func() { p.names = outer }()
+	}
+
+	body, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return &iteration{name: name.text, list: list, body: body}, nil
+}
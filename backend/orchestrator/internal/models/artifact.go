@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Artifact describes a node output spilled to the artifact store. ID is
+// also the blob's key in the store.
+type Artifact struct {
+	ID          string    `bson:"_id" json:"id"`
+	RunID       string    `bson:"runId" json:"runId"`
+	NodeID      string    `bson:"nodeId" json:"nodeId"`
+	Key         string    `bson:"key" json:"key"`
+	ContentType string    `bson:"contentType" json:"contentType"`
+	Size        int64     `bson:"size" json:"size"`
+	CreatedAt   time.Time `bson:"createdAt" json:"createdAt"`
+}
@@ -7,11 +7,17 @@ import (
 )
 
 type ExecutionLog struct {
-	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	WorkflowID  string            `bson:"workflowId" json:"workflowId"`
-	TaskName   string             `bson:"taskName" json:"taskName"`
-	Status     string             `bson:"status" json:"status"` // started, running, completed, failed
-	Timestamp  time.Time          `bson:"timestamp" json:"timestamp"`
-	Description string            `bson:"description,omitempty" json:"description,omitempty"`
-	Details    map[string]interface{} `bson:"details,omitempty" json:"details,omitempty"`
+	ID          primitive.ObjectID     `bson:"_id,omitempty" json:"id,omitempty"`
+	WorkflowID  string                 `bson:"workflowId" json:"workflowId"`
+	RunID       string                 `bson:"runId,omitempty" json:"runId,omitempty"`
+	NodeID      string                 `bson:"nodeId,omitempty" json:"nodeId,omitempty"`
+	TaskName    string                 `bson:"taskName" json:"taskName"`
+	Status      string                 `bson:"status" json:"status"` // started, running, completed, failed
+	Attempt     int                    `bson:"attempt,omitempty" json:"attempt,omitempty"`
+	Error       string                 `bson:"error,omitempty" json:"error,omitempty"`
+	ErrorClass  string                 `bson:"errorClass,omitempty" json:"errorClass,omitempty"`
+	DurationMs  int64                  `bson:"durationMs,omitempty" json:"durationMs,omitempty"`
+	Timestamp   time.Time              `bson:"timestamp" json:"timestamp"`
+	Description string                 `bson:"description,omitempty" json:"description,omitempty"`
+	Details     map[string]interface{} `bson:"details,omitempty" json:"details,omitempty"`
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Recording is one executor attempt of a run, stored in the "recordings"
+// collection so the run can be replayed. Seq counts the node's executions
+// within the run (loop iterations), starting at 0.
+type Recording struct {
+	ID         primitive.ObjectID     `bson:"_id,omitempty" json:"id,omitempty"`
+	RunID      string                 `bson:"runId" json:"runId"`
+	NodeID     string                 `bson:"nodeId" json:"nodeId"`
+	Type       string                 `bson:"type" json:"type"`
+	Seq        int                    `bson:"seq" json:"seq"`
+	Attempt    int                    `bson:"attempt" json:"attempt"`
+	Input      map[string]interface{} `bson:"input,omitempty" json:"input,omitempty"`   // data after template resolution
+	Output     map[string]interface{} `bson:"output,omitempty" json:"output,omitempty"` // data after the executor ran
+	Next       string                 `bson:"next,omitempty" json:"next,omitempty"`
+	Error      string                 `bson:"error,omitempty" json:"error,omitempty"`
+	ErrorClass string                 `bson:"errorClass,omitempty" json:"errorClass,omitempty"`
+	Replayed   bool                   `bson:"replayed,omitempty" json:"replayed,omitempty"` // fed from a recording instead of executed
+	Timestamp  time.Time              `bson:"timestamp" json:"timestamp"`
+}
+
+// Divergence is a difference between a replay and the run it replays.
+type Divergence struct {
+	NodeID   string      `bson:"nodeId" json:"nodeId"`
+	Seq      int         `bson:"seq" json:"seq"`
+	Kind     string      `bson:"kind" json:"kind"` // missing, extra, branch, error, input, output
+	Message  string      `bson:"message" json:"message"`
+	Expected interface{} `bson:"expected,omitempty" json:"expected,omitempty"`
+	Actual   interface{} `bson:"actual,omitempty" json:"actual,omitempty"`
+}
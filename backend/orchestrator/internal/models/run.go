@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Run is a single execution of a workflow, stored in the "runs" collection.
+type Run struct {
+	ID         primitive.ObjectID     `bson:"_id,omitempty" json:"id,omitempty"`
+	WorkflowID string                 `bson:"workflowId" json:"workflowId"`
+	Status     string                 `bson:"status" json:"status"` // queued, running, paused, completed, failed, cancelled
+	Input      map[string]interface{} `bson:"input,omitempty" json:"input,omitempty"`
+	Nodes      []RunNode              `bson:"nodes" json:"nodes"`
+	Error      string                 `bson:"error,omitempty" json:"error,omitempty"`
+
+	// Outputs are the run's results: the workflow's named outputs as declared
+	// in OutputDefs, or else the results of its end nodes.
+	Outputs    map[string]interface{} `bson:"outputs,omitempty" json:"outputs,omitempty"`
+	OutputDefs []WorkflowOutput       `bson:"outputDefs,omitempty" json:"outputDefs,omitempty"`
+
+	// Start and MaxConcurrency, together with each node's Next, snapshot the
+	// graph so an unfinished run can be resumed after a restart even if the
+	// workflow was edited in the meantime.
+	Start          string `bson:"start" json:"start"`
+	MaxConcurrency int    `bson:"maxConcurrency,omitempty" json:"maxConcurrency,omitempty"`
+
+	// Definition is the node list as the run started, before any node
+	// wrote results into its data. Replays start from it.
+	Definition []RunNode `bson:"definition,omitempty" json:"-"`
+
+	// ReplayOf is set on a replay run to the run it replays; Divergences
+	// lists where the replay differed from it.
+	ReplayOf    string       `bson:"replayOf,omitempty" json:"replayOf,omitempty"`
+	Divergences []Divergence `bson:"divergences,omitempty" json:"divergences,omitempty"`
+
+	// Subworkflow linkage: a child run points at its parent run, the parent
+	// lists its children.
+	ParentRunID  string   `bson:"parentRunId,omitempty" json:"parentRunId,omitempty"`
+	ParentNodeID string   `bson:"parentNodeId,omitempty" json:"parentNodeId,omitempty"`
+	Depth        int      `bson:"depth,omitempty" json:"depth,omitempty"`
+	ChildRunIDs  []string `bson:"childRunIds,omitempty" json:"childRunIds,omitempty"`
+
+	CreatedAt  time.Time  `bson:"createdAt" json:"createdAt"`
+	StartedAt  *time.Time `bson:"startedAt,omitempty" json:"startedAt,omitempty"`
+	FinishedAt *time.Time `bson:"finishedAt,omitempty" json:"finishedAt,omitempty"`
+}
+
+// RunNode is the per-node result of a run.
+type RunNode struct {
+	ID         string                 `bson:"id" json:"id"`
+	Type       string                 `bson:"type" json:"type"`
+	Label      string                 `bson:"label,omitempty" json:"label,omitempty"`
+	Status     string                 `bson:"status" json:"status"`
+	Data       map[string]interface{} `bson:"data,omitempty" json:"data,omitempty"`
+	Next       []string               `bson:"next,omitempty" json:"next,omitempty"`
+	ErrorNext  []string               `bson:"errorNext,omitempty" json:"errorNext,omitempty"`
+	Body       []string               `bson:"body,omitempty" json:"body,omitempty"`
+	Cases      map[string]string      `bson:"cases,omitempty" json:"cases,omitempty"`
+	LambdaName string                 `bson:"lambdaName,omitempty" json:"lambdaName,omitempty"`
+	Branch     string                 `bson:"branch,omitempty" json:"branch,omitempty"`
+	Error      string                 `bson:"error,omitempty" json:"error,omitempty"`
+	ErrorClass string                 `bson:"errorClass,omitempty" json:"errorClass,omitempty"`
+	Attempts   int                    `bson:"attempts,omitempty" json:"attempts,omitempty"`
+	StartedAt  *time.Time             `bson:"startedAt,omitempty" json:"startedAt,omitempty"`
+	FinishedAt *time.Time             `bson:"finishedAt,omitempty" json:"finishedAt,omitempty"`
+}
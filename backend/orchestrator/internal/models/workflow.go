@@ -22,6 +22,19 @@ type Workflow struct {
 	// Canvas-friendly representation (nodes & edges)
 	Nodes       []Node       `bson:"nodes,omitempty" json:"nodes,omitempty"`
 	Connections []Connection `bson:"connections,omitempty" json:"connections,omitempty"`
+
+	// InputSchema is the JSON Schema runs are started with; Outputs name the
+	// values a run returns.
+	InputSchema map[string]interface{} `bson:"inputSchema,omitempty" json:"inputSchema,omitempty"`
+	Outputs     []WorkflowOutput       `bson:"outputs,omitempty" json:"outputs,omitempty"`
+}
+
+// WorkflowOutput is a named result of a workflow. Value is usually a
+// template over the run context, e.g. "{{ nodes.ai1.output }}".
+type WorkflowOutput struct {
+	Name        string      `bson:"name" json:"name"`
+	Value       interface{} `bson:"value" json:"value"`
+	Description string      `bson:"description,omitempty" json:"description,omitempty"`
 }
 
 // Task is the older unit of work representation (kept for compatibility)
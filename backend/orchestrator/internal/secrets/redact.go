@@ -0,0 +1,140 @@
+package secrets
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Mask replaces secret values in redacted text.
+const Mask = "[REDACTED]"
+
+// minTracked is the shortest value worth redacting; masking every "1" or
+// "on" would make logs unreadable without protecting anything.
+const minTracked = 4
+
+// tracked holds every secret value this process has decrypted. Values are
+// masked wherever they end up: logs, execution logs and stored node data.
+var tracked = struct {
+	sync.RWMutex
+	values   map[string]bool
+	replacer *strings.Replacer
+}{values: map[string]bool{}}
+
+// Track registers plaintext values for redaction.
+func Track(values ...string) {
+	tracked.Lock()
+	defer tracked.Unlock()
+
+	added := false
+	for _, v := range values {
+		if len(v) >= minTracked && !tracked.values[v] {
+			tracked.values[v] = true
+			added = true
+		}
+	}
+	if !added {
+		return
+	}
+
+	// Longest first, so a value containing another is masked whole.
+	all := make([]string, 0, len(tracked.values))
+	for v := range tracked.values {
+		all = append(all, v)
+	}
+	sort.Slice(all, func(i, j int) bool { return len(all[i]) > len(all[j]) })
+	pairs := make([]string, 0, 2*len(all))
+	for _, v := range all {
+		pairs = append(pairs, v, Mask)
+	}
+	tracked.replacer = strings.NewReplacer(pairs...)
+}
+
+// Redact masks every tracked secret value in s.
+func Redact(s string) string {
+	tracked.RLock()
+	r := tracked.replacer
+	tracked.RUnlock()
+	if r == nil {
+		return s
+	}
+	return r.Replace(s)
+}
+
+// RedactValue returns a copy of v, a JSON-like value, with every tracked
+// secret masked in its strings. v itself is not modified.
+func RedactValue(v interface{}) interface{} {
+	tracked.RLock()
+	none := tracked.replacer == nil
+	tracked.RUnlock()
+	if none {
+		return v
+	}
+	return redactValue(v)
+}
+
+func redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case string:
+		return Redact(t)
+	case map[string]interface{}:
+		return redactMap(t)
+	case primitive.M:
+		return primitive.M(redactMap(t))
+	case []interface{}:
+		return redactList(t)
+	case primitive.A:
+		return primitive.A(redactList(t))
+	case []string:
+		out := make([]string, len(t))
+		for i, s := range t {
+			out[i] = Redact(s)
+		}
+		return out
+	}
+	return v
+}
+
+func redactMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, e := range m {
+		out[k] = redactValue(e)
+	}
+	return out
+}
+
+func redactList(list []interface{}) []interface{} {
+	out := make([]interface{}, len(list))
+	for i, e := range list {
+		out[i] = redactValue(e)
+	}
+	return out
+}
+
+// RedactData is RedactValue for node data.
+func RedactData(data map[string]interface{}) map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+	return RedactValue(data).(map[string]interface{})
+}
+
+// Writer wraps w so that everything written through it is redacted. It is
+// meant for the standard logger, which writes one line per call.
+func Writer(w io.Writer) io.Writer {
+	return redactWriter{w}
+}
+
+type redactWriter struct {
+	w io.Writer
+}
+
+func (r redactWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,82 @@
+// Package secrets encrypts credentials at rest and keeps their plaintext
+// out of logs.
+//
+// Values are sealed with AES-256-GCM under a master key read from the
+// SECRETS_MASTER_KEY environment variable: 32 random bytes, base64 encoded
+// (e.g. `openssl rand -base64 32`). The secret's name is bound to the
+// ciphertext as additional data, so a sealed value cannot be moved to
+// another name.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeyEnv is the environment variable holding the master key.
+const KeyEnv = "SECRETS_MASTER_KEY"
+
+// ErrNoKey is returned when the master key is not configured.
+var ErrNoKey = errors.New("secrets store is not configured: " + KeyEnv + " is not set")
+
+// aead returns the cipher for the configured master key.
+func aead() (cipher.AEAD, error) {
+	raw := strings.TrimSpace(os.Getenv(KeyEnv))
+	if raw == "" {
+		return nil, ErrNoKey
+	}
+	key, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not valid base64: %w", KeyEnv, err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("%s must decode to 32 bytes, got %d", KeyEnv, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Configured reports whether a usable master key is set.
+func Configured() error {
+	_, err := aead()
+	return err
+}
+
+// Seal encrypts the value of the named secret. It returns a fresh nonce and
+// the ciphertext.
+func Seal(name, plaintext string) (nonce, ciphertext []byte, err error) {
+	gcm, err := aead()
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return nonce, gcm.Seal(nil, nonce, []byte(plaintext), []byte(name)), nil
+}
+
+// Open decrypts a value sealed by Seal for the same name.
+func Open(name string, nonce, ciphertext []byte) (string, error) {
+	gcm, err := aead()
+	if err != nil {
+		return "", err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return "", fmt.Errorf("secret %s: invalid nonce", name)
+	}
+	plain, err := gcm.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("secret %s: cannot decrypt (wrong master key?)", name)
+	}
+	return string(plain), nil
+}
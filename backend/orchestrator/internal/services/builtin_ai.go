@@ -2,12 +2,15 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+
+	wapp "github.com/Davanesh/auto-orchestrator/internal/executors"
 )
 
 type AIExecutor struct{}
@@ -16,7 +19,7 @@ func init() {
 	RegisterExecutor("ai", &AIExecutor{})
 }
 
-func (e *AIExecutor) Execute(node *ExecNode, g *ExecGraph) (string, error) {
+func (e *AIExecutor) Execute(ctx context.Context, node *ExecNode, g *ExecGraph) (string, error) {
 	prompt := fmt.Sprintf("%v", node.Data["prompt"])
 	input := fmt.Sprintf("%v", node.Data["input"])
 
@@ -33,19 +36,28 @@ func (e *AIExecutor) Execute(node *ExecNode, g *ExecGraph) (string, error) {
 
 	jsonBody, _ := json.Marshal(reqBody)
 
-	resp, err := http.Post(
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
 		"http://localhost:11434/api/generate",
-		"application/json",
 		bytes.NewBuffer(jsonBody),
 	)
 	if err != nil {
 		return "", err
 	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
 	defer resp.Body.Close()
 
 	respBytes, _ := io.ReadAll(resp.Body)
 	log.Println("🔍 RAW OLLAMA RESPONSE:", string(respBytes))
 
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", wapp.NewHTTPError("ollama", resp.StatusCode, string(respBytes))
+	}
+
 	var fullResponse string
 	decoder := json.NewDecoder(bytes.NewReader(respBytes))
 
@@ -73,3 +85,22 @@ func (e *AIExecutor) Execute(node *ExecNode, g *ExecGraph) (string, error) {
 	node.Status = "done"
 	return "", nil
 }
+
+func (e *AIExecutor) Schema() NodeSchema {
+	return NodeSchema{
+		Inputs: &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"prompt": {Type: "string"},
+				"input":  {Type: "string"},
+			},
+			AnyOf: []*Schema{{Required: []string{"prompt"}}, {Required: []string{"input"}}},
+		},
+		Outputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"output": {Type: "string", Description: "the model's answer"},
+		}},
+	}
+}
+
+// HasSideEffects: model replies are not reproducible; replays use the recorded reply.
+func (e *AIExecutor) HasSideEffects() bool { return true }
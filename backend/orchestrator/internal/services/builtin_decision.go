@@ -1,13 +1,19 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
+	"strconv"
+	"strings"
+
+	"github.com/Davanesh/auto-orchestrator/internal/expr"
 )
 
 type DecisionExecutor struct{}
 
-func (d *DecisionExecutor) Execute(node *ExecNode, g *ExecGraph) (string, error) {
+func (d *DecisionExecutor) Execute(ctx context.Context, node *ExecNode, g *ExecGraph) (string, error) {
 	log.Printf("🟣 Decision node: %s", node.Label)
 	node.Status = "running"
 
@@ -16,25 +22,20 @@ func (d *DecisionExecutor) Execute(node *ExecNode, g *ExecGraph) (string, error)
 		return "", errors.New("decision node missing condition")
 	}
 
-	// Normalize to string
-	condStr := ""
-	switch v := cond.(type) {
-	case string:
-		condStr = v
-	case bool:
-		if v {
-			condStr = "true"
-		} else {
-			condStr = "false"
-		}
-	default:
-		return "", errors.New("invalid condition type")
+	truthy, err := evalCondition(cond, g)
+	if err != nil {
+		return "", err
 	}
 
 	node.Status = "done"
 
+	// Edges bound to the "true" / "false" cases win over connection order.
+	if target, ok := node.caseTarget(strconv.FormatBool(truthy)); ok {
+		return target, nil
+	}
+
 	// Yes / True branch = Next[0]
-	if condStr == "true" || condStr == "yes" || condStr == "1" {
+	if truthy {
 		if len(node.Next) > 0 {
 			return node.Next[0], nil
 		}
@@ -49,6 +50,86 @@ func (d *DecisionExecutor) Execute(node *ExecNode, g *ExecGraph) (string, error)
 	return "", errors.New("missing false branch")
 }
 
+func (d *DecisionExecutor) Schema() NodeSchema {
+	return NodeSchema{
+		Inputs: &Schema{Type: "object", Required: []string{"condition"}, Properties: map[string]*Schema{
+			"condition": {AnyOf: []*Schema{{Type: "boolean"}, {Type: "string"}}, Description: "boolean, \"yes\"/\"no\" or an expression; true takes the first edge"},
+		}},
+	}
+}
+
+func (d *DecisionExecutor) ValidateData(nodeID string, data map[string]interface{}) []FieldError {
+	return validateCondition(nodeID, "data.condition", data["condition"])
+}
+
 func init() {
 	RegisterExecutor("decision", &DecisionExecutor{})
 }
+
+// isTruthy interprets a literal condition: a bool, or one of the strings
+// "true", "yes" and "1". Any other string is false.
+func isTruthy(cond interface{}) (bool, error) {
+	switch v := cond.(type) {
+	case string:
+		return v == "true" || v == "yes" || v == "1", nil
+	case bool:
+		return v, nil
+	}
+	return false, errors.New("invalid condition type")
+}
+
+// literalConditions are the condition strings read as plain booleans rather
+// than as expressions.
+var literalConditions = map[string]bool{
+	"true": true, "yes": true, "1": true,
+	"false": false, "no": false, "0": false, "": false,
+}
+
+// evalCondition evaluates a decision or loop condition: a bool, a literal
+// such as "yes", or an expression over the run context (see package expr),
+// e.g. len(nodes.wait1.input) > 0 && contains(lower(nodes.wait1.input), "yes").
+func evalCondition(cond interface{}, g *ExecGraph) (bool, error) {
+	s, ok := cond.(string)
+	if !ok {
+		return isTruthy(cond)
+	}
+	if v, ok := literalConditions[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return v, nil
+	}
+	if hasTemplate(s) {
+		return false, Classified(ErrorClassConfig, errTemplateCondition)
+	}
+
+	prog, err := expr.Compile(s, ContextNames...)
+	if err != nil {
+		return false, Classified(ErrorClassConfig, fmt.Errorf("condition: %w", err))
+	}
+	ok, err = prog.EvalBool(g.TemplateContext())
+	if err != nil {
+		return false, fmt.Errorf("condition %q: %w", s, err)
+	}
+	return ok, nil
+}
+
+// errTemplateCondition rejects {{ }} in conditions, which are not
+// template-resolved (see expressionKeys).
+var errTemplateCondition = errors.New("templates are not allowed in conditions; refer to nodes, trigger and vars directly, e.g. nodes.ai1.output == \"yes\"")
+
+// validateCondition compiles a condition expression so mistakes are
+// reported when the workflow is saved.
+func validateCondition(nodeID, field string, cond interface{}) []FieldError {
+	s, ok := cond.(string)
+	if !ok {
+		return nil
+	}
+	if _, ok := literalConditions[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return nil
+	}
+	if hasTemplate(s) {
+		return []FieldError{{Node: nodeID, Field: field, Message: errTemplateCondition.Error()}}
+	}
+	if _, err := expr.Compile(s, ContextNames...); err != nil {
+		return []FieldError{{Node: nodeID, Field: field, Message: err.Error()}}
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	wapp "github.com/Davanesh/auto-orchestrator/internal/executors"
+)
+
+// maxHTTPResponse bounds the response body an http node reads. Large bodies
+// end up in the artifact store once the node is done.
+const maxHTTPResponse = 32 << 20
+
+func init() {
+	RegisterExecutor("http", &HTTPExecutor{})
+}
+
+// HTTPExecutor calls a REST API.
+//
+// Data:
+//
+//	method        GET (default), POST, PUT, PATCH, DELETE, HEAD
+//	url           http(s) URL, usually with templates
+//	query         {"name": value} added to the URL's query
+//	headers       {"Name": "value"}
+//	body          an object or list (sent as JSON, or as a form with
+//	              bodyType "form") or a string (sent as is)
+//	bodyType      json | form | text; default json, text for string bodies
+//	auth          {"type": "basic", "username": ..., "password": ...}
+//	              {"type": "bearer", "token": ...}
+//	              {"type": "header", "name": "X-Api-Key", "value": ...}
+//	              The password, token or value may instead come from the
+//	              secret named by "secret".
+//	responseType  auto (by Content-Type, default) | json | text | form
+//	ignoreStatus  treat non-2xx answers as success
+//
+// The response is stored in Data["output"] as {status, headers, body}. A
+// non-2xx answer fails the node with an HTTPError (after storing the
+// response), so it can be retried or routed to an error edge.
+type HTTPExecutor struct{}
+
+func (e *HTTPExecutor) Execute(ctx context.Context, node *ExecNode, g *ExecGraph) (string, error) {
+	node.Status = "running"
+
+	req, err := buildHTTPRequest(ctx, node.Data)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("🌐 HTTP node %s: %s %s", node.Label, req.Method, req.URL.Redacted())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPResponse+1))
+	if err != nil {
+		return "", err
+	}
+	if len(raw) > maxHTTPResponse {
+		return "", fmt.Errorf("response larger than %d bytes", maxHTTPResponse)
+	}
+
+	responseType, _ := node.Data["responseType"].(string)
+	body, err := parseHTTPBody(raw, resp.Header.Get("Content-Type"), responseType)
+	if err != nil {
+		return "", err
+	}
+
+	headers := map[string]interface{}{}
+	for name, values := range resp.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ", ")
+	}
+	node.Data["output"] = map[string]interface{}{
+		"status":  resp.StatusCode,
+		"headers": headers,
+		"body":    body,
+	}
+
+	ignoreStatus, _ := node.Data["ignoreStatus"].(bool)
+	if !ignoreStatus && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		return "", wapp.NewHTTPError("http", resp.StatusCode, string(raw))
+	}
+
+	node.Status = "done"
+	return "", nil
+}
+
+// buildHTTPRequest builds the request described by an http node's data.
+func buildHTTPRequest(ctx context.Context, data map[string]interface{}) (*http.Request, error) {
+	method := http.MethodGet
+	if m, ok := data["method"].(string); ok && m != "" {
+		method = strings.ToUpper(m)
+	}
+
+	rawURL, _ := data["url"].(string)
+	u, err := parseHTTPURL(rawURL)
+	if err != nil {
+		return nil, Classified(ErrorClassConfig, err)
+	}
+	if query, ok := asMap(data["query"]); ok {
+		q := u.Query()
+		for k, v := range query {
+			if list, ok := asSlice(v); ok {
+				for _, item := range list {
+					q.Add(k, stringify(item))
+				}
+				continue
+			}
+			q.Set(k, stringify(v))
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	var body io.Reader
+	contentType := ""
+	if v, ok := data["body"]; ok && v != nil && method != http.MethodGet && method != http.MethodHead {
+		bodyType, _ := data["bodyType"].(string)
+		b, ct, err := encodeHTTPBody(v, bodyType)
+		if err != nil {
+			return nil, Classified(ErrorClassConfig, err)
+		}
+		body = bytes.NewReader(b)
+		contentType = ct
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, Classified(ErrorClassConfig, err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if headers, ok := asMap(data["headers"]); ok {
+		for k, v := range headers {
+			req.Header.Set(k, stringify(v))
+		}
+	}
+	if auth, ok := asMap(data["auth"]); ok {
+		if err := applyHTTPAuth(ctx, req, auth); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+func parseHTTPURL(raw string) (*url.URL, error) {
+	if raw == "" {
+		return nil, errors.New("http node requires a 'url'")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("url must be http or https, got %q", raw)
+	}
+	return u, nil
+}
+
+// encodeHTTPBody serializes a request body.
+func encodeHTTPBody(v interface{}, bodyType string) ([]byte, string, error) {
+	if s, ok := v.(string); ok && (bodyType == "" || bodyType == "text") {
+		return []byte(s), "text/plain; charset=utf-8", nil
+	}
+
+	switch bodyType {
+	case "", "json":
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, "", fmt.Errorf("body: %w", err)
+		}
+		return b, "application/json", nil
+	case "form":
+		fields, ok := asMap(v)
+		if !ok {
+			return nil, "", errors.New("a form body must be an object")
+		}
+		form := url.Values{}
+		for k, e := range fields {
+			if list, ok := asSlice(e); ok {
+				for _, item := range list {
+					form.Add(k, stringify(item))
+				}
+				continue
+			}
+			form.Set(k, stringify(e))
+		}
+		return []byte(form.Encode()), "application/x-www-form-urlencoded", nil
+	case "text":
+		return []byte(stringify(v)), "text/plain; charset=utf-8", nil
+	}
+	return nil, "", fmt.Errorf("unknown bodyType %q", bodyType)
+}
+
+// applyHTTPAuth adds the configured credentials to req.
+func applyHTTPAuth(ctx context.Context, req *http.Request, auth map[string]interface{}) error {
+	// credential returns the field, or the secret named by "secret".
+	credential := func(field string) (string, error) {
+		if name, ok := auth["secret"].(string); ok && name != "" {
+			return secretValue(ctx, name)
+		}
+		if auth[field] == nil {
+			return "", Classified(ErrorClassConfig, fmt.Errorf("auth requires %q or \"secret\"", field))
+		}
+		return stringify(auth[field]), nil
+	}
+
+	switch t, _ := auth["type"].(string); t {
+	case "", "none":
+	case "basic":
+		password, err := credential("password")
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(stringify(auth["username"]), password)
+	case "bearer":
+		token, err := credential("token")
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case "header":
+		name, _ := auth["name"].(string)
+		if name == "" {
+			return Classified(ErrorClassConfig, errors.New("header auth requires a 'name'"))
+		}
+		value, err := credential("value")
+		if err != nil {
+			return err
+		}
+		req.Header.Set(name, value)
+	default:
+		return Classified(ErrorClassConfig, fmt.Errorf("unknown auth type %q", t))
+	}
+	return nil
+}
+
+// parseHTTPBody decodes a response body. "auto" picks the format from the
+// Content-Type and falls back to text.
+func parseHTTPBody(raw []byte, contentType, responseType string) (interface{}, error) {
+	if responseType == "" || responseType == "auto" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		switch {
+		case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+			responseType = "json"
+		case mediaType == "application/x-www-form-urlencoded":
+			responseType = "form"
+		default:
+			responseType = "text"
+		}
+	}
+
+	switch responseType {
+	case "json":
+		if len(bytes.TrimSpace(raw)) == 0 {
+			return nil, nil
+		}
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, fmt.Errorf("response is not valid JSON: %w", err)
+		}
+		return v, nil
+	case "form":
+		values, err := url.ParseQuery(string(raw))
+		if err != nil {
+			return nil, fmt.Errorf("response is not a valid form: %w", err)
+		}
+		out := make(map[string]interface{}, len(values))
+		for k, v := range values {
+			if len(v) == 1 {
+				out[k] = v[0]
+				continue
+			}
+			list := make([]interface{}, len(v))
+			for i, s := range v {
+				list[i] = s
+			}
+			out[k] = list
+		}
+		return out, nil
+	case "text":
+		return string(raw), nil
+	}
+	return nil, Classified(ErrorClassConfig, fmt.Errorf("unknown responseType %q", responseType))
+}
+
+func (e *HTTPExecutor) Schema() NodeSchema {
+	return NodeSchema{
+		Config: &Schema{Type: "object", Required: []string{"url"}, Properties: map[string]*Schema{
+			"method":       {Type: "string", Enum: []interface{}{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"}, IgnoreCase: true, Default: "GET"},
+			"url":          {Type: "string", MinLength: 1},
+			"query":        {Type: "object"},
+			"headers":      {Type: "object"},
+			"bodyType":     {Type: "string", Enum: []interface{}{"json", "form", "text"}},
+			"responseType": {Type: "string", Enum: []interface{}{"auto", "json", "text", "form"}, Default: "auto"},
+			"ignoreStatus": {Type: "boolean", Description: "treat non-2xx answers as success"},
+			"auth": {Type: "object", Required: []string{"type"}, Properties: map[string]*Schema{
+				"type":   {Type: "string", Enum: []interface{}{"none", "basic", "bearer", "header"}},
+				"secret": {Type: "string", Description: "name of the secret holding the password, token or header value"},
+			}},
+		}},
+		Inputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"body": {Description: "request body: object, list or string"},
+		}},
+		Outputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"output": {Type: "object", Description: "{status, headers, body}"},
+		}},
+	}
+}
+
+func (e *HTTPExecutor) ValidateData(nodeID string, data map[string]interface{}) []FieldError {
+	var errs []FieldError
+	if raw, ok := data["url"].(string); ok && raw != "" && !hasTemplate(raw) {
+		if _, err := parseHTTPURL(raw); err != nil {
+			errs = append(errs, FieldError{Node: nodeID, Field: "data.url", Message: err.Error()})
+		}
+	}
+
+	auth, ok := asMap(data["auth"])
+	if !ok {
+		return errs
+	}
+	var need []string
+	switch auth["type"] {
+	case "basic":
+		need = []string{"username", "password"}
+	case "bearer":
+		need = []string{"token"}
+	case "header":
+		need = []string{"name", "value"}
+	}
+	for _, field := range need {
+		if auth[field] != nil {
+			continue
+		}
+		if _, fromSecret := auth["secret"]; fromSecret && field != "username" && field != "name" {
+			continue
+		}
+		errs = append(errs, FieldError{Node: nodeID, Field: "data.auth." + field, Message: "is required"})
+	}
+	return errs
+}
+
+// HasSideEffects: the called API may change state; replays use the recorded response.
+func (e *HTTPExecutor) HasSideEffects() bool { return true }
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+func init() {
+	RegisterExecutor("join", &JoinExecutor{})
+}
+
+// JoinExecutor merges the branches arriving at a node with several incoming
+// edges. The scheduler decides when it runs and hands it the arrived
+// branches in Data["arrivals"] (arrival order) and Data["inputs"] (result by
+// source node id).
+//
+// Data:
+//
+//	mode     "all" (default) waits for every incoming edge, "any" runs on the
+//	         first arrived branch, "quorum" once `quorum` branches arrived
+//	quorum   N for mode "quorum"
+//	merge    "object" (default) result keyed by source node id, "list" in
+//	         arrival order, or "first" for the first arrived result
+type JoinExecutor struct{}
+
+func (e *JoinExecutor) Execute(ctx context.Context, node *ExecNode, g *ExecGraph) (string, error) {
+	log.Printf("🔀 Join node: %s", node.Label)
+	node.Status = "running"
+
+	if _, err := joinRequirement(node); err != nil {
+		return "", err
+	}
+
+	arrivals, _ := asSlice(node.Data["arrivals"])
+	inputs, _ := asMap(node.Data["inputs"])
+
+	merge := "object"
+	if v, ok := node.Data["merge"].(string); ok && v != "" {
+		merge = strings.ToLower(v)
+	}
+
+	switch merge {
+	case "object":
+		out := map[string]interface{}{}
+		for k, v := range inputs {
+			out[k] = v
+		}
+		node.Data["output"] = out
+	case "list":
+		out := []interface{}{}
+		for _, id := range arrivals {
+			out = append(out, inputs[fmt.Sprintf("%v", id)])
+		}
+		node.Data["output"] = out
+	case "first":
+		if len(arrivals) > 0 {
+			node.Data["output"] = inputs[fmt.Sprintf("%v", arrivals[0])]
+		}
+	default:
+		return "", Classified(ErrorClassConfig, fmt.Errorf("unknown join merge: %s", merge))
+	}
+
+	node.Data["branches"] = len(arrivals)
+	node.Status = "done"
+	return "", nil
+}
+
+func (e *JoinExecutor) Schema() NodeSchema {
+	return NodeSchema{
+		Config: &Schema{Type: "object", Properties: map[string]*Schema{
+			"mode":   {Type: "string", Enum: []interface{}{"all", "any", "quorum"}},
+			"quorum": {Type: "integer", Minimum: atLeast(1)},
+			"merge":  {Type: "string", Enum: []interface{}{"object", "list", "first"}},
+		}},
+		Outputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"branches": {Type: "integer"},
+		}},
+	}
+}
+
+// joinRequirement returns how many branches must arrive before the join may
+// run early; 0 means it waits for every incoming edge.
+func joinRequirement(n *ExecNode) (int, error) {
+	mode := "all"
+	if v, ok := n.Data["mode"].(string); ok && v != "" {
+		mode = strings.ToLower(v)
+	}
+
+	switch mode {
+	case "all":
+		return 0, nil
+	case "any":
+		return 1, nil
+	case "quorum":
+		q := dataInt(n.Data, "quorum")
+		if q < 1 {
+			return 0, Classified(ErrorClassConfig, errors.New("join quorum must be >= 1"))
+		}
+		return q, nil
+	}
+	return 0, Classified(ErrorClassConfig, fmt.Errorf("unknown join mode: %s", mode))
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+func init() {
+	RegisterExecutor("lambda", &LambdaExecutor{})
+}
+
+// LambdaExecutor invokes an AWS Lambda function.
+//
+// Data:
+//
+//	functionName    name or ARN; defaults to the node's lambdaName
+//	invocationType  RequestResponse (default): wait for the result;
+//	                Event: queue the call and continue right away
+//	endpointUrl     custom endpoint, e.g. a local emulator; must be
+//	                LAMBDA_ENDPOINT_URL or listed in LAMBDA_ENDPOINT_URLS.
+//	                Defaults to LAMBDA_ENDPOINT_URL, then AWS
+//	payload         what to send; defaults to the run context
+//	                ({run, nodes, trigger, vars}, as seen by templates)
+//
+// The function's JSON response is stored in Data["output"] (a response that
+// is not JSON is stored as text) and the invocation status in
+// Data["statusCode"]. A function error fails the node; its payload is kept
+// in Data["output"].
+type LambdaExecutor struct{}
+
+func (e *LambdaExecutor) Execute(ctx context.Context, node *ExecNode, g *ExecGraph) (string, error) {
+	node.Status = "running"
+
+	name, _ := node.Data["functionName"].(string)
+	if name == "" {
+		name = node.LambdaName
+	}
+	if name == "" {
+		return "", Classified(ErrorClassConfig, errors.New("lambda node requires a 'functionName'"))
+	}
+
+	invocationType := types.InvocationTypeRequestResponse
+	switch t, _ := node.Data["invocationType"].(string); t {
+	case "", string(types.InvocationTypeRequestResponse):
+	case string(types.InvocationTypeEvent):
+		invocationType = types.InvocationTypeEvent
+	default:
+		return "", Classified(ErrorClassConfig, fmt.Errorf("unknown invocationType %q", t))
+	}
+
+	payload, ok := node.Data["payload"]
+	if !ok {
+		scope := g.TemplateContext()
+		scope["run"] = map[string]interface{}{
+			"id":         g.RunID,
+			"workflowId": g.WorkflowID,
+			"nodeId":     node.ID,
+		}
+		payload = scope
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", Classified(ErrorClassConfig, fmt.Errorf("payload: %w", err))
+	}
+
+	endpoint := strings.TrimSpace(stringify(node.Data["endpointUrl"]))
+	invoker, err := lambdaInvoker(ctx, endpoint)
+	if err != nil {
+		return "", Classified(ErrorClassConfig, err)
+	}
+
+	log.Printf("λ Lambda node %s: invoking %s (%s)", node.Label, name, invocationType)
+	res, err := invoker.Invoke(ctx, name, body, invocationType)
+	if res != nil {
+		node.Data["statusCode"] = res.StatusCode
+		node.Data["output"] = lambdaOutput(res.Payload)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	node.Status = "done"
+	return "", nil
+}
+
+// lambdaOutput decodes a function's response: JSON if it parses, text
+// otherwise, nothing for an empty (Event) response.
+func lambdaOutput(payload []byte) interface{} {
+	if len(payload) == 0 {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return string(payload)
+	}
+	return v
+}
+
+func (e *LambdaExecutor) Schema() NodeSchema {
+	return NodeSchema{
+		Config: &Schema{Type: "object", Properties: map[string]*Schema{
+			"functionName":   {Type: "string", Description: "defaults to the node's lambdaName"},
+			"invocationType": {Type: "string", Enum: []interface{}{"RequestResponse", "Event"}, Default: "RequestResponse"},
+			"endpointUrl":    {Type: "string", Description: "custom endpoint, e.g. a local emulator"},
+		}},
+		Inputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"payload": {Description: "payload to send; defaults to the run context"},
+		}},
+		Outputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"output":     {Description: "the function's response"},
+			"statusCode": {Type: "integer"},
+		}},
+	}
+}
+
+// ValidateData checks that endpointUrl is a fixed, allowed endpoint.
+func (e *LambdaExecutor) ValidateData(nodeID string, data map[string]interface{}) []FieldError {
+	v, ok := data["endpointUrl"]
+	if !ok {
+		return nil
+	}
+	fail := func(msg string) []FieldError {
+		return []FieldError{{Node: nodeID, Field: "data.endpointUrl", Message: msg}}
+	}
+	if hasTemplate(v) {
+		return fail("templates are not allowed in endpointUrl")
+	}
+	if s := strings.TrimSpace(stringify(v)); s != "" {
+		if err := checkLambdaEndpoint(s); err != nil {
+			return fail(err.Error())
+		}
+	}
+	return nil
+}
+
+// HasSideEffects: a replay must not invoke the function again.
+func (e *LambdaExecutor) HasSideEffects() bool { return true }
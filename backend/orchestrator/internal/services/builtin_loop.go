@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterExecutor("loop", &LoopExecutor{})
+}
+
+// LoopExecutor repeats the nodes on its "body" edges. Edges from the body
+// back to the loop node end an iteration; the loop's normal edges are
+// followed once it is done.
+//
+// Data:
+//
+//	mode            "while" (check before each iteration, default) or "until"
+//	                (check after each iteration)
+//	maxIterations   required upper bound
+//	condition       a bool, a literal such as "yes" or an expression over
+//	                the run context, e.g. "vars.page < 10" (see evalCondition)
+//	conditionNode   read the condition from a node instead...
+//	conditionKey    ...using this key of its data (default "output")
+//	onMax           "fail" (default) or "exit" when maxIterations is reached
+//
+// Before every iteration each body node gets Data["loop"] with the 1-based
+// iteration counter and the results of the previous iterations. The loop's
+// own output is the list of per-iteration results.
+type LoopExecutor struct{}
+
+func (e *LoopExecutor) Execute(ctx context.Context, node *ExecNode, g *ExecGraph) (string, error) {
+	log.Printf("🔁 Loop node: %s", node.Label)
+	node.Status = "running"
+
+	max := dataInt(node.Data, "maxIterations")
+	if max <= 0 {
+		return "", Classified(ErrorClassConfig, errors.New("loop node requires maxIterations > 0"))
+	}
+	if len(node.Body) == 0 {
+		return "", Classified(ErrorClassConfig, errors.New("loop node has no body edge"))
+	}
+
+	mode := "while"
+	if v, ok := node.Data["mode"].(string); ok && v != "" {
+		mode = strings.ToLower(v)
+	}
+	if mode != "while" && mode != "until" {
+		return "", Classified(ErrorClassConfig, fmt.Errorf("unknown loop mode: %s", mode))
+	}
+
+	body, err := g.reachableFrom(node.Body, node.ID)
+	if err != nil {
+		return "", err
+	}
+	initial := snapshotNodes(g, body)
+
+	results := []interface{}{}
+	iterations := 0
+	for {
+		if mode == "while" {
+			more, err := loopCondition(node, g)
+			if err != nil {
+				return "", err
+			}
+			if !more {
+				break
+			}
+		}
+
+		if iterations >= max {
+			if node.Data["onMax"] == "exit" {
+				break
+			}
+			return "", fmt.Errorf("loop %s reached maxIterations (%d)", node.ID, max)
+		}
+		iterations++
+
+		resetNodes(g, initial, map[string]interface{}{
+			"iteration": iterations,
+			"results":   append([]interface{}(nil), results...),
+		})
+
+		if err := runRegion(ctx, g, node.Body, node.ID); err != nil {
+			return "", err
+		}
+		results = append(results, iterationResult(g, body))
+
+		if mode == "until" {
+			done, err := loopCondition(node, g)
+			if err != nil {
+				return "", err
+			}
+			if done {
+				break
+			}
+		}
+	}
+
+	node.Data["iterations"] = iterations
+	node.Data["results"] = results
+	node.Data["output"] = results
+	node.Status = "done"
+	return "", nil
+}
+
+func (e *LoopExecutor) Schema() NodeSchema {
+	return NodeSchema{
+		Config: &Schema{Type: "object", Required: []string{"maxIterations"}, Properties: map[string]*Schema{
+			"mode":          {Type: "string", Enum: []interface{}{"while", "until"}},
+			"maxIterations": {Type: "integer", Minimum: atLeast(1)},
+			"conditionNode": {Type: "string"},
+			"conditionKey":  {Type: "string"},
+			"onMax":         {Type: "string", Enum: []interface{}{"fail", "exit"}},
+		}},
+		Inputs: &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"condition": {AnyOf: []*Schema{{Type: "boolean"}, {Type: "string"}}},
+			},
+			AnyOf: []*Schema{{Required: []string{"condition"}}, {Required: []string{"conditionNode"}}},
+		},
+		Outputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"iterations": {Type: "integer"},
+			"output":     {Type: "array", Description: "per-iteration results"},
+		}},
+	}
+}
+
+func (e *LoopExecutor) ValidateData(nodeID string, data map[string]interface{}) []FieldError {
+	return validateCondition(nodeID, "data.condition", data["condition"])
+}
+
+// loopCondition evaluates the loop's condition against the current state
+// of the graph.
+func loopCondition(node *ExecNode, g *ExecGraph) (bool, error) {
+	if id, ok := node.Data["conditionNode"].(string); ok && id != "" {
+		key := "output"
+		if k, ok := node.Data["conditionKey"].(string); ok && k != "" {
+			key = k
+		}
+
+		g.mu.RLock()
+		src, ok := g.Nodes[id]
+		var v interface{}
+		if ok {
+			v = src.Data[key]
+		}
+		g.mu.RUnlock()
+
+		if !ok {
+			return false, Classified(ErrorClassConfig, errors.New("loop conditionNode not found: "+id))
+		}
+		if v == nil {
+			return false, nil
+		}
+		return isTruthy(v)
+	}
+
+	cond, ok := node.Data["condition"]
+	if !ok {
+		return false, Classified(ErrorClassConfig, errors.New("loop node missing condition"))
+	}
+	return evalCondition(cond, g)
+}
+
+// snapshotNodes copies the body nodes as they are before the first
+// iteration so every iteration starts from the same configuration.
+func snapshotNodes(g *ExecGraph, ids map[string]bool) map[string]*ExecNode {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	out := make(map[string]*ExecNode, len(ids))
+	for id := range ids {
+		out[id] = g.Nodes[id].clone()
+	}
+	return out
+}
+
+// resetNodes restores the body nodes to their snapshot and hands them the
+// loop state for the coming iteration.
+func resetNodes(g *ExecGraph, initial map[string]*ExecNode, state map[string]interface{}) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for id, orig := range initial {
+		n := g.Nodes[id]
+		n.Data = orig.clone().Data
+		n.Data["loop"] = state
+		n.Status = "pending"
+		n.Branch = ""
+		n.Error = ""
+		n.ErrorClass = ""
+		n.Attempts = 0
+		n.StartedAt = time.Time{}
+		n.FinishedAt = time.Time{}
+	}
+}
+
+// iterationResult collects the result of every body node that ran in the
+// last iteration.
+func iterationResult(g *ExecGraph, ids map[string]bool) map[string]interface{} {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	out := map[string]interface{}{}
+	for id := range ids {
+		n := g.Nodes[id]
+		if n.Status != "done" {
+			continue
+		}
+		if v, ok := n.result(); ok {
+			out[id] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	wapp "github.com/Davanesh/auto-orchestrator/internal/executors"
+)
+
+func init() {
+	RegisterExecutor("rules", &RulesExecutor{})
+}
+
+// RulesExecutor evaluates a decision table (see executors/rule_executor.go
+// for the cell syntax), so pricing or routing rules can change without
+// touching the graph.
+//
+// Data:
+//
+//	hitPolicy  "first" (default) or "collect"
+//	columns    [{"name": "amount", "expr": "trigger.body.amount"}, ...]
+//	rules      [{"when": {"amount": ">= 100"}, "then": {"discount": 10}}, ...]
+//	default    output of a "first" table when no rule matches
+//
+// Under "first" Data["output"] is the "then" of the first matching rule;
+// under "collect" it is the list of all of them. Data["matched"] lists the
+// indexes of the matching rules.
+type RulesExecutor struct{}
+
+func (e *RulesExecutor) Execute(ctx context.Context, node *ExecNode, g *ExecGraph) (string, error) {
+	log.Printf("📋 Rules node: %s", node.Label)
+	node.Status = "running"
+
+	table, rules, err := compileRules(node.Data)
+	if err != nil {
+		return "", Classified(ErrorClassConfig, err)
+	}
+
+	matched, err := table.Evaluate(g.TemplateContext())
+	if err != nil {
+		return "", err
+	}
+
+	if table.HitPolicy() == wapp.HitCollect {
+		out := make([]interface{}, len(matched))
+		for i, r := range matched {
+			out[i] = rules[r].Then
+		}
+		node.Data["output"] = out
+	} else if len(matched) > 0 {
+		node.Data["output"] = rules[matched[0]].Then
+	} else {
+		node.Data["output"] = node.Data["default"]
+	}
+	node.Data["matched"] = matched
+
+	node.Status = "done"
+	return "", nil
+}
+
+func (e *RulesExecutor) Schema() NodeSchema {
+	return NodeSchema{
+		Config: &Schema{Type: "object", Required: []string{"columns", "rules"}, Properties: map[string]*Schema{
+			"hitPolicy": {Type: "string", Enum: []interface{}{wapp.HitFirst, wapp.HitCollect}, Default: wapp.HitFirst},
+			"columns": {Type: "array", Items: &Schema{
+				Type:     "object",
+				Required: []string{"name", "expr"},
+				Properties: map[string]*Schema{
+					"name": {Type: "string", MinLength: 1},
+					"expr": {Type: "string", MinLength: 1, Description: "expression over the run context"},
+				},
+			}},
+			"rules": {Type: "array", Items: &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"when": {Type: "object", Description: "condition cells keyed by column name"},
+					"then": {Type: "object", Description: "outputs of the rule"},
+				},
+			}},
+			"default": {Description: "output when no rule matches (first policy)"},
+		}},
+		Outputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"output":  {Description: "the matching rule's outputs, or a list of them"},
+			"matched": {Type: "array", Items: &Schema{Type: "integer"}},
+		}},
+	}
+}
+
+func (e *RulesExecutor) ValidateData(nodeID string, data map[string]interface{}) []FieldError {
+	if hasTemplate(data["columns"]) || hasTemplate(data["rules"]) {
+		return nil
+	}
+	t, err := ruleTable(data)
+	if err != nil {
+		return nil // reported by the schema
+	}
+	_, errs := wapp.CompileRules(t, ContextNames...)
+	out := make([]FieldError, len(errs))
+	for i, e := range errs {
+		out[i] = FieldError{Node: nodeID, Field: "data." + e.Field, Message: e.Err.Error()}
+	}
+	return out
+}
+
+// compileRules reads and compiles the decision table in data. It returns the
+// first problem found; ValidateData reports all of them.
+func compileRules(data map[string]interface{}) (*wapp.CompiledRules, []wapp.Rule, error) {
+	t, err := ruleTable(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	table, errs := wapp.CompileRules(t, ContextNames...)
+	if len(errs) > 0 {
+		return nil, nil, errs[0]
+	}
+	return table, t.Rules, nil
+}
+
+// ruleTable reads Data's hitPolicy, columns and rules.
+func ruleTable(data map[string]interface{}) (wapp.RuleTable, error) {
+	var t wapp.RuleTable
+	t.HitPolicy, _ = data["hitPolicy"].(string)
+
+	columns, ok := asSlice(data["columns"])
+	if !ok {
+		return t, errors.New("rules node requires a 'columns' list")
+	}
+	for i, item := range columns {
+		c, ok := asMap(item)
+		if !ok {
+			return t, fmt.Errorf("column %d must be an object", i)
+		}
+		name, _ := c["name"].(string)
+		src, _ := c["expr"].(string)
+		t.Columns = append(t.Columns, wapp.RuleColumn{Name: name, Expr: src})
+	}
+
+	rules, ok := asSlice(data["rules"])
+	if !ok {
+		return t, errors.New("rules node requires a 'rules' list")
+	}
+	for i, item := range rules {
+		r, ok := asMap(item)
+		if !ok {
+			return t, fmt.Errorf("rule %d must be an object", i)
+		}
+		when, _ := asMap(r["when"])
+		cells := make(map[string]interface{}, len(when))
+		for k, v := range when {
+			// Lists read back from Mongo are primitive.A.
+			if list, ok := asSlice(v); ok {
+				v = []interface{}(list)
+			}
+			cells[k] = v
+		}
+		then, _ := asMap(r["then"])
+		t.Rules = append(t.Rules, wapp.Rule{When: cells, Then: then})
+	}
+	return t, nil
+}
@@ -1,15 +1,34 @@
 package services
 
-import "log"
+import (
+	"context"
+	"log"
+)
 
 type StartExecutor struct{}
 
-func (s *StartExecutor) Execute(node *ExecNode, g *ExecGraph) (string, error) {
+func (s *StartExecutor) Execute(ctx context.Context, node *ExecNode, g *ExecGraph) (string, error) {
 	log.Printf("🟢 Start node: %s", node.Label)
+
+	// Expose the run input to the rest of the graph.
+	if g.Input != nil {
+		node.Data["input"] = g.Input
+	}
 	node.Status = "done"
 	return "", nil
 }
 
+func (s *StartExecutor) Schema() NodeSchema {
+	return NodeSchema{
+		Config: &Schema{Type: "object", Properties: map[string]*Schema{
+			"vars": {Type: "object", Description: "default run variables, overridden by the input's \"vars\""},
+		}},
+		Outputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"input": {Type: "object", Description: "the run input"},
+		}},
+	}
+}
+
 func init() {
 	RegisterExecutor("start", &StartExecutor{})
 }
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// StateStore keeps key/value state that outlives runs. Every operation must
+// be atomic with respect to concurrent runs. Keys past their TTL behave as
+// missing. A zero ttl leaves an existing expiry unchanged, except for Set,
+// which replaces the key entirely.
+type StateStore interface {
+	Get(ctx context.Context, ns, key string) (value interface{}, found bool, err error)
+	Set(ctx context.Context, ns, key string, value interface{}, ttl time.Duration) error
+	Incr(ctx context.Context, ns, key string, by float64, ttl time.Duration) (float64, error)
+	// Append adds value to the list at key. With unique set the list is a
+	// set: added reports whether value was not in it yet.
+	Append(ctx context.Context, ns, key string, value interface{}, unique bool, ttl time.Duration) (list []interface{}, added bool, err error)
+	Delete(ctx context.Context, ns, key string) (found bool, err error)
+}
+
+var stateStore StateStore
+
+// SetStateStore installs the store used by state nodes. The api package
+// provides it, since it owns the database.
+func SetStateStore(s StateStore) {
+	stateStore = s
+}
+
+func init() {
+	RegisterExecutor("state", &StateExecutor{})
+}
+
+// StateExecutor reads and writes persistent state: counters, "already
+// greeted this number" flags, dedupe sets.
+//
+// Data:
+//
+//	op          get | set | incr | append | delete
+//	key         the key (usually a template)
+//	scope       "workflow" (default): shared by all runs of this workflow;
+//	            "contact": shared by every workflow talking to the contact
+//	contact     the contact's ID, e.g. "{{ trigger.body.From }}"; required
+//	            with scope "contact"
+//	value       for set and append
+//	by          for incr, default 1
+//	unique      for append: treat the list as a set
+//	default     for get, returned when the key is missing
+//	ttlSeconds  optional expiry of the key, counted from this write
+//
+// The result is stored in Data["output"]: the value for get and set, the new
+// number for incr, the new list for append and whether the key existed for
+// delete. get also sets Data["found"], append Data["added"].
+type StateExecutor struct{}
+
+func (e *StateExecutor) Execute(ctx context.Context, node *ExecNode, g *ExecGraph) (string, error) {
+	log.Printf("🗄️ State node: %s", node.Label)
+	node.Status = "running"
+
+	if stateStore == nil {
+		return "", Classified(ErrorClassConfig, errors.New("state store is not available"))
+	}
+
+	op := fmt.Sprintf("%v", node.Data["op"])
+	key := ""
+	if v := node.Data["key"]; v != nil {
+		key = strings.TrimSpace(fmt.Sprintf("%v", v))
+	}
+	if key == "" {
+		return "", Classified(ErrorClassConfig, errors.New("state node requires a 'key'"))
+	}
+	ns, err := stateNamespace(node.Data, g)
+	if err != nil {
+		return "", Classified(ErrorClassConfig, err)
+	}
+	ttl := time.Duration(dataInt(node.Data, "ttlSeconds")) * time.Second
+
+	switch op {
+	case "get":
+		v, found, err := stateStore.Get(ctx, ns, key)
+		if err != nil {
+			return "", err
+		}
+		if !found {
+			v = node.Data["default"]
+		}
+		node.Data["output"] = v
+		node.Data["found"] = found
+
+	case "set":
+		v := node.Data["value"]
+		if err := stateStore.Set(ctx, ns, key, v, ttl); err != nil {
+			return "", err
+		}
+		node.Data["output"] = v
+
+	case "incr":
+		by := 1.0
+		if raw, ok := node.Data["by"]; ok {
+			if by, ok = toFloat(raw); !ok {
+				return "", Classified(ErrorClassConfig, fmt.Errorf("'by' must be a number, got %v", raw))
+			}
+		}
+		n, err := stateStore.Incr(ctx, ns, key, by, ttl)
+		if err != nil {
+			return "", err
+		}
+		node.Data["output"] = n
+
+	case "append":
+		unique, _ := node.Data["unique"].(bool)
+		list, added, err := stateStore.Append(ctx, ns, key, node.Data["value"], unique, ttl)
+		if err != nil {
+			return "", err
+		}
+		node.Data["output"] = list
+		node.Data["added"] = added
+
+	case "delete":
+		found, err := stateStore.Delete(ctx, ns, key)
+		if err != nil {
+			return "", err
+		}
+		node.Data["output"] = found
+
+	default:
+		return "", Classified(ErrorClassConfig, fmt.Errorf("unknown state op %q", op))
+	}
+
+	node.Status = "done"
+	return "", nil
+}
+
+// stateNamespace returns the namespace a state node works in.
+func stateNamespace(data map[string]interface{}, g *ExecGraph) (string, error) {
+	switch scope, _ := data["scope"].(string); scope {
+	case "", "workflow":
+		if g.WorkflowID == "" {
+			return "", errors.New("workflow scope needs a saved workflow")
+		}
+		return "workflow:" + g.WorkflowID, nil
+	case "contact":
+		contact := strings.TrimSpace(fmt.Sprintf("%v", data["contact"]))
+		if data["contact"] == nil || contact == "" {
+			return "", errors.New("contact scope requires a 'contact'")
+		}
+		return "contact:" + contact, nil
+	default:
+		return "", fmt.Errorf("unknown state scope %q", scope)
+	}
+}
+
+func (e *StateExecutor) Schema() NodeSchema {
+	return NodeSchema{
+		Config: &Schema{Type: "object", Required: []string{"op", "key"}, Properties: map[string]*Schema{
+			"op":         {Type: "string", Enum: []interface{}{"get", "set", "incr", "append", "delete"}},
+			"key":        {Type: "string", MinLength: 1},
+			"scope":      {Type: "string", Enum: []interface{}{"workflow", "contact"}, Default: "workflow"},
+			"by":         {Type: "number", Description: "incr step, default 1"},
+			"unique":     {Type: "boolean", Description: "append: keep the list free of duplicates"},
+			"ttlSeconds": {Type: "integer", Minimum: atLeast(0)},
+		}},
+		Inputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"contact": {Type: "string", Description: "contact ID for scope \"contact\""},
+			"value":   {Description: "value to set or append"},
+			"default": {Description: "get: value when the key is missing"},
+		}},
+		Outputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"output": {Description: "value, new count, new list or whether the key existed"},
+			"found":  {Type: "boolean", Description: "get: whether the key existed"},
+			"added":  {Type: "boolean", Description: "append: whether the value was added"},
+		}},
+	}
+}
+
+// HasSideEffects: state has moved on since the run; replays use the values it saw.
+func (e *StateExecutor) HasSideEffects() bool { return true }
+
+func (e *StateExecutor) ValidateData(nodeID string, data map[string]interface{}) []FieldError {
+	var errs []FieldError
+	op, _ := data["op"].(string)
+	if _, ok := data["value"]; !ok && (op == "set" || op == "append") {
+		errs = append(errs, FieldError{Node: nodeID, Field: "data.value", Message: op + " requires a value"})
+	}
+	if scope, _ := data["scope"].(string); scope == "contact" && data["contact"] == nil {
+		errs = append(errs, FieldError{Node: nodeID, Field: "data.contact", Message: "contact scope requires a contact"})
+	}
+	return errs
+}
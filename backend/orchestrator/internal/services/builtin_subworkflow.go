@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// MaxSubworkflowDepth bounds how deeply subworkflow nodes may nest. It also
+// stops a workflow that (directly or not) calls itself.
+const MaxSubworkflowDepth = 5
+
+// ChildRun is the outcome of a subworkflow's child run.
+type ChildRun struct {
+	RunID   string
+	Status  string
+	Outputs map[string]interface{}
+}
+
+// SubworkflowRunner loads the saved workflow workflowID and runs it as a
+// child of the given parent node, blocking until the child finishes. The api
+// package provides it, since it owns workflow loading and run records.
+type SubworkflowRunner func(ctx context.Context, parent *ExecGraph, parentNode *ExecNode, workflowID string, input map[string]interface{}) (*ChildRun, error)
+
+var subworkflowRunner SubworkflowRunner
+
+// SetSubworkflowRunner installs the function used by subworkflow nodes.
+func SetSubworkflowRunner(r SubworkflowRunner) {
+	subworkflowRunner = r
+}
+
+func init() {
+	RegisterExecutor("subworkflow", &SubworkflowExecutor{})
+}
+
+// SubworkflowExecutor runs another saved workflow as a child run.
+//
+// Data:
+//
+//	workflowId   id of the workflow to run
+//	input        object passed as the child run's input
+//
+// The child's outputs become this node's "output"; its run id is stored in
+// "childRunId".
+type SubworkflowExecutor struct{}
+
+func (e *SubworkflowExecutor) Execute(ctx context.Context, node *ExecNode, g *ExecGraph) (string, error) {
+	log.Printf("🧩 Subworkflow node: %s", node.Label)
+	node.Status = "running"
+
+	workflowID, _ := node.Data["workflowId"].(string)
+	if workflowID == "" {
+		return "", Classified(ErrorClassConfig, errors.New("subworkflow node requires 'workflowId'"))
+	}
+	if g.Depth+1 > MaxSubworkflowDepth {
+		return "", Classified(ErrorClassConfig, fmt.Errorf("subworkflow depth limit (%d) reached", MaxSubworkflowDepth))
+	}
+	if subworkflowRunner == nil {
+		return "", errors.New("subworkflow runner not configured")
+	}
+
+	input := map[string]interface{}{}
+	if v, ok := node.Data["input"]; ok && v != nil {
+		m, ok := asMap(v)
+		if !ok {
+			return "", Classified(ErrorClassConfig, errors.New("subworkflow 'input' must be an object"))
+		}
+		input = m
+	}
+
+	child, err := subworkflowRunner(ctx, g, node, workflowID, input)
+	if child != nil {
+		node.Data["childRunId"] = child.RunID
+	}
+	if err != nil {
+		return "", err
+	}
+
+	node.Data["output"] = child.Outputs
+	node.Status = "done"
+	return "", nil
+}
+
+func (e *SubworkflowExecutor) Schema() NodeSchema {
+	return NodeSchema{
+		Config: &Schema{Type: "object", Required: []string{"workflowId"}, Properties: map[string]*Schema{
+			"workflowId": {Type: "string", Pattern: "^[0-9a-fA-F]{24}$"},
+		}},
+		Inputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"input": {Type: "object"},
+		}},
+		Outputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"childRunId": {Type: "string"},
+			"output":     {Type: "object", Description: "the child run's outputs"},
+		}},
+	}
+}
+
+// HasSideEffects: replays use the recorded child outputs rather than starting a child run.
+func (e *SubworkflowExecutor) HasSideEffects() bool { return true }
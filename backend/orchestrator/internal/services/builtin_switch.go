@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+func init() {
+	RegisterExecutor("switch", &SwitchExecutor{})
+}
+
+// SwitchExecutor follows one of N named branches. Every outgoing edge names
+// its case in its metadata ({"case": "sales"}); an edge with
+// {"default": true} is taken when no case matches.
+//
+// Data:
+//
+//	cases   [{"name": "sales", "when": "<expression>"}, ...] evaluated in
+//	        order, the first true one wins; or, together with value,
+//	        [{"name": "sales", "value": "sales"}, ...]
+//	value   compared with each case's value (usually a template)
+//
+// Cases are not template-resolved; a when expression reads nodes, trigger
+// and vars itself.
+//
+// The chosen case is stored in Data["case"].
+type SwitchExecutor struct{}
+
+func (e *SwitchExecutor) Execute(ctx context.Context, node *ExecNode, g *ExecGraph) (string, error) {
+	log.Printf("🔀 Switch node: %s", node.Label)
+	node.Status = "running"
+
+	cases, err := switchCases(node.Data)
+	if err != nil {
+		return "", Classified(ErrorClassConfig, err)
+	}
+
+	value, byValue := node.Data["value"]
+	chosen := ""
+	for _, c := range cases {
+		var match bool
+		if byValue {
+			match = fmt.Sprintf("%v", c["value"]) == fmt.Sprintf("%v", value)
+		} else {
+			match, err = evalCondition(c["when"], g)
+			if err != nil {
+				return "", fmt.Errorf("case %s: %w", c["name"], err)
+			}
+		}
+		if match {
+			chosen = fmt.Sprintf("%v", c["name"])
+			break
+		}
+	}
+	if chosen == "" {
+		chosen = "default"
+	}
+
+	target, ok := node.caseTarget(chosen)
+	if !ok {
+		if chosen == "default" {
+			return "", errors.New("no case matched and the switch has no default edge")
+		}
+		return "", Classified(ErrorClassConfig, fmt.Errorf("case %s has no edge", chosen))
+	}
+
+	node.Data["case"] = chosen
+	node.Status = "done"
+	return target, nil
+}
+
+func (e *SwitchExecutor) Schema() NodeSchema {
+	return NodeSchema{
+		Config: &Schema{Type: "object", Required: []string{"cases"}, Properties: map[string]*Schema{
+			"cases": {Type: "array", Items: &Schema{
+				Type:     "object",
+				Required: []string{"name"},
+				Properties: map[string]*Schema{
+					"name": {Type: "string", MinLength: 1},
+					"when": {AnyOf: []*Schema{{Type: "boolean"}, {Type: "string"}}},
+				},
+			}},
+		}},
+		Inputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"value": {Description: "compared with each case's value"},
+		}},
+		Outputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"case": {Type: "string", Description: "the case taken"},
+		}},
+	}
+}
+
+func (e *SwitchExecutor) ValidateData(nodeID string, data map[string]interface{}) []FieldError {
+	cases, err := switchCases(data)
+	if err != nil {
+		return nil // reported by the schema
+	}
+	var errs []FieldError
+	for i, c := range cases {
+		errs = append(errs, validateCondition(nodeID, fmt.Sprintf("data.cases[%d].when", i), c["when"])...)
+	}
+	return errs
+}
+
+// ValidateEdges checks that the declared cases and the case names on the
+// outgoing edges match one to one.
+func (e *SwitchExecutor) ValidateEdges(n *ExecNode) []FieldError {
+	fail := func(field, format string, args ...interface{}) FieldError {
+		return FieldError{Node: n.ID, Field: field, Message: fmt.Sprintf(format, args...)}
+	}
+
+	cases, err := switchCases(n.Data)
+	if err != nil {
+		return nil
+	}
+
+	var errs []FieldError
+	declared := map[string]bool{}
+	for i, c := range cases {
+		name := fmt.Sprintf("%v", c["name"])
+		field := fmt.Sprintf("data.cases[%d].name", i)
+		if name == "default" {
+			errs = append(errs, fail(field, "\"default\" is reserved for the default edge"))
+		}
+		if declared[name] {
+			errs = append(errs, fail(field, "duplicate case %q", name))
+		}
+		declared[name] = true
+		if _, ok := n.caseTarget(name); !ok {
+			errs = append(errs, fail(field, "case %q has no outgoing edge", name))
+		}
+	}
+
+	bound := map[string]string{}
+	for _, target := range n.Next {
+		name, ok := n.Cases[target]
+		switch {
+		case !ok:
+			errs = append(errs, fail("connections", "edge to %s does not name a case", target))
+		case name != "default" && !declared[name]:
+			errs = append(errs, fail("connections", "edge to %s names unknown case %q", target, name))
+		case bound[name] != "":
+			errs = append(errs, fail("connections", "edges to %s and %s both name case %q", bound[name], target, name))
+		default:
+			bound[name] = target
+		}
+	}
+	return errs
+}
+
+// switchCases reads Data["cases"] as a list of objects.
+func switchCases(data map[string]interface{}) ([]map[string]interface{}, error) {
+	list, ok := asSlice(data["cases"])
+	if !ok {
+		return nil, errors.New("switch node requires a 'cases' list")
+	}
+	out := make([]map[string]interface{}, 0, len(list))
+	for i, item := range list {
+		c, ok := asMap(item)
+		if !ok || c["name"] == nil {
+			return nil, fmt.Errorf("switch case %d must be an object with a name", i)
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
@@ -1,23 +1,24 @@
 package services
 
 import (
+	"context"
 	"log"
 	"time"
 )
 
 type TaskExecutor struct{}
 
-func (t *TaskExecutor) Execute(node *ExecNode, g *ExecGraph) (string, error) {
+func (t *TaskExecutor) Execute(ctx context.Context, node *ExecNode, g *ExecGraph) (string, error) {
 	log.Printf("🟡 Task node: %s", node.Label)
 	node.Status = "running"
 
 	// Simulated work
-	if v, ok := node.Data["sleepMs"]; ok {
-		if ms, ok2 := v.(float64); ok2 {
-			time.Sleep(time.Duration(int(ms)) * time.Millisecond)
-		}
-	} else {
-		time.Sleep(500 * time.Millisecond)
+	d := 500 * time.Millisecond
+	if _, ok := node.Data["sleepMs"]; ok {
+		d = time.Duration(dataInt(node.Data, "sleepMs")) * time.Millisecond
+	}
+	if err := sleepCtx(ctx, d); err != nil {
+		return "", err
 	}
 
 	node.Status = "done"
@@ -25,6 +26,17 @@ func (t *TaskExecutor) Execute(node *ExecNode, g *ExecGraph) (string, error) {
 	return "", nil
 }
 
+func (t *TaskExecutor) Schema() NodeSchema {
+	return NodeSchema{
+		Config: &Schema{Type: "object", Properties: map[string]*Schema{
+			"sleepMs": {Type: "number", Minimum: atLeast(0), Description: "simulated work, default 500"},
+		}},
+	}
+}
+
+// HasSideEffects: tasks stand for external work, which replays do not repeat.
+func (t *TaskExecutor) HasSideEffects() bool { return true }
+
 func init() {
 	RegisterExecutor("task", &TaskExecutor{})
 }
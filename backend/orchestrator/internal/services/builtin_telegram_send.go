@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	wapp "github.com/Davanesh/auto-orchestrator/internal/executors"
+)
+
+func init() {
+	RegisterExecutor("telegram_send", &TelegramSendExecutor{})
+}
+
+type TelegramSendExecutor struct{}
+
+func (e *TelegramSendExecutor) Execute(ctx context.Context, n *ExecNode, g *ExecGraph) (string, error) {
+	n.Status = "running"
+
+	// Chat IDs are numbers; the canvas may store them as either.
+	chatID := stringify(n.Data["chatId"])
+	if chatID == "" {
+		n.Status = "failed"
+		return "", Classified(ErrorClassConfig, errors.New("missing 'chatId' in telegram_send node"))
+	}
+
+	// "message" is the configured text, usually a template such as
+	// {{ nodes.ai1.output }}; otherwise the node's input is sent.
+	body := stringify(n.Data["message"])
+	if body == "" {
+		body = stringify(n.Data["input"])
+	}
+	if body == "" {
+		body = "(empty message)"
+	}
+
+	parseMode, _ := n.Data["parseMode"].(string)
+	id, err := wapp.SendTelegramMessage(ctx, chatID, body, parseMode)
+	if err != nil {
+		n.Status = "failed"
+		return "", err
+	}
+
+	n.Data["output"] = body
+	n.Data["messageId"] = id
+	n.Status = "done"
+	return "", nil
+}
+
+func (e *TelegramSendExecutor) Schema() NodeSchema {
+	return NodeSchema{
+		Config: &Schema{Type: "object", Required: []string{"chatId"}, Properties: map[string]*Schema{
+			"chatId":    {AnyOf: []*Schema{{Type: "string", MinLength: 1}, {Type: "integer"}}, Description: "numeric chat ID or @channel"},
+			"parseMode": {Type: "string", Enum: []interface{}{"", "HTML", "Markdown", "MarkdownV2"}},
+		}},
+		Inputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"message": {Type: "string"},
+			"input":   {Type: "string"},
+		}},
+		Outputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"output":    {Type: "string", Description: "the text sent"},
+			"messageId": {Type: "integer"},
+		}},
+	}
+}
+
+// HasSideEffects: a replay must not message the chat again.
+func (e *TelegramSendExecutor) HasSideEffects() bool { return true }
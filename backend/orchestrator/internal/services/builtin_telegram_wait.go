@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+
+	wapp "github.com/Davanesh/auto-orchestrator/internal/executors"
+)
+
+func init() {
+	RegisterExecutor("telegram_wait", &TelegramWaitExecutor{})
+}
+
+type TelegramWaitExecutor struct{}
+
+// Execute blocks until a message arrives from the node's chatId (any chat if
+// unset). The wait is bounded by the node's timeoutSeconds, which the engine
+// applies to ctx.
+func (e *TelegramWaitExecutor) Execute(ctx context.Context, n *ExecNode, g *ExecGraph) (string, error) {
+	n.Status = "running"
+
+	msg, err := wapp.WaitForTelegramMessage(ctx, g.RunID, n.ID, stringify(n.Data["chatId"]))
+	if err != nil {
+		n.Status = "failed"
+		return "", err
+	}
+
+	n.Data["input"] = msg.Text
+	n.Data["message"] = map[string]interface{}{
+		"chatId":    msg.ChatID,
+		"messageId": msg.MessageID,
+		"from":      msg.From,
+		"text":      msg.Text,
+		"date":      msg.Date,
+	}
+	n.Status = "done"
+	return "", nil
+}
+
+func (e *TelegramWaitExecutor) Schema() NodeSchema {
+	return NodeSchema{
+		Config: &Schema{Type: "object", Properties: map[string]*Schema{
+			"chatId": {AnyOf: []*Schema{{Type: "string"}, {Type: "integer"}}, Description: "only accept messages from this chat"},
+		}},
+		Outputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"input":   {Type: "string", Description: "the message text"},
+			"message": {Type: "object", Description: "chatId, messageId, from, text and date"},
+		}},
+	}
+}
+
+// HasSideEffects: replays use the recorded message instead of waiting for a new one.
+func (e *TelegramWaitExecutor) HasSideEffects() bool { return true }
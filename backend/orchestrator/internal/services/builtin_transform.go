@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/Davanesh/auto-orchestrator/internal/expr"
+)
+
+func init() {
+	RegisterExecutor("transform", &TransformExecutor{})
+}
+
+// TransformExecutor builds a new JSON value from the run context, typically
+// to reshape a Twilio, Ollama or Lambda payload for the next node.
+//
+// Data:
+//
+//	mapping   an object whose leaves are expressions (see package expr), e.g.
+//	          {"name":  "upper(trigger.body.name)",
+//	           "age":   "number(default(trigger.body.age, 0))",
+//	           "tags":  "map(nodes.ai1.output.tags, item.label)",
+//	           "adult": "filter(trigger.body.people, item.age >= 18)"}
+//	          Nested objects and lists are built recursively; numbers, booleans
+//	          and null are kept as they are. A plain string needs quotes:
+//	          "'fixed text'". The mapping may also be a single expression.
+//
+// The result is stored in Data["output"].
+type TransformExecutor struct{}
+
+func (e *TransformExecutor) Execute(ctx context.Context, node *ExecNode, g *ExecGraph) (string, error) {
+	log.Printf("🧩 Transform node: %s", node.Label)
+	node.Status = "running"
+
+	mapping, ok := node.Data["mapping"]
+	if !ok {
+		return "", Classified(ErrorClassConfig, errors.New("transform node requires a 'mapping'"))
+	}
+
+	out, err := Transform(mapping, g.TemplateContext())
+	if err != nil {
+		return "", err
+	}
+
+	node.Data["output"] = out
+	node.Status = "done"
+	return "", nil
+}
+
+func (e *TransformExecutor) Schema() NodeSchema {
+	return NodeSchema{
+		Config: &Schema{Type: "object", Required: []string{"mapping"}, Properties: map[string]*Schema{
+			"mapping": {
+				AnyOf:       []*Schema{{Type: "object"}, {Type: "string"}},
+				Description: "object whose leaves are expressions over the run context, or one expression",
+			},
+		}},
+		Outputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"output": {Description: "the value built by the mapping"},
+		}},
+	}
+}
+
+func (e *TransformExecutor) ValidateData(nodeID string, data map[string]interface{}) []FieldError {
+	mapping, ok := data["mapping"]
+	if !ok {
+		return nil // reported by the schema
+	}
+	return ValidateMapping(nodeID, "data.mapping", mapping)
+}
+
+// Transform evaluates a transform mapping against scope, which has the shape
+// of TemplateContext. Compile errors are config errors.
+func Transform(mapping interface{}, scope map[string]interface{}) (interface{}, error) {
+	return transformValue("mapping", mapping, scope)
+}
+
+func transformValue(field string, v interface{}, scope map[string]interface{}) (interface{}, error) {
+	if s, ok := v.(string); ok {
+		prog, err := expr.Compile(s, ContextNames...)
+		if err != nil {
+			return nil, Classified(ErrorClassConfig, fmt.Errorf("%s: %w", field, err))
+		}
+		out, err := prog.Eval(scope)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field, err)
+		}
+		return out, nil
+	}
+	if m, ok := asMap(v); ok {
+		out := make(map[string]interface{}, len(m))
+		for k, e := range m {
+			r, err := transformValue(field+"."+k, e, scope)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	}
+	if list, ok := asSlice(v); ok {
+		out := make([]interface{}, len(list))
+		for i, e := range list {
+			r, err := transformValue(fmt.Sprintf("%s[%d]", field, i), e, scope)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// ValidateMapping compiles every expression of a mapping so mistakes are
+// reported when the workflow is saved.
+func ValidateMapping(nodeID, field string, mapping interface{}) []FieldError {
+	if s, ok := mapping.(string); ok {
+		if hasTemplate(s) {
+			return nil
+		}
+		if _, err := expr.Compile(s, ContextNames...); err != nil {
+			return []FieldError{{Node: nodeID, Field: field, Message: err.Error()}}
+		}
+		return nil
+	}
+
+	var errs []FieldError
+	if m, ok := asMap(mapping); ok {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			errs = append(errs, ValidateMapping(nodeID, field+"."+k, m[k])...)
+		}
+	}
+	if list, ok := asSlice(mapping); ok {
+		for i, e := range list {
+			errs = append(errs, ValidateMapping(nodeID, fmt.Sprintf("%s[%d]", field, i), e)...)
+		}
+	}
+	return errs
+}
@@ -1,7 +1,7 @@
 package services
 
 import (
-	"strconv"
+	"context"
 	"time"
 )
 
@@ -11,22 +11,25 @@ func init() {
 
 type WaitExecutor struct{}
 
-func (e *WaitExecutor) Execute(n *ExecNode, g *ExecGraph) (string, error) {
+func (e *WaitExecutor) Execute(ctx context.Context, n *ExecNode, g *ExecGraph) (string, error) {
 	n.Status = "running"
 
-	secs := 0
-	if v, ok := n.Data["waitSeconds"]; ok {
-		switch t := v.(type) {
-		case float64:
-			secs = int(t)
-		case int:
-			secs = t
-		case string:
-			secs, _ = strconv.Atoi(t)
-		}
+	secs := dataInt(n.Data, "waitSeconds")
+	if err := sleepCtx(ctx, time.Duration(secs)*time.Second); err != nil {
+		return "", err
 	}
 
-	time.Sleep(time.Duration(secs) * time.Second)
 	n.Status = "done"
 	return "", nil
 }
+
+func (e *WaitExecutor) Schema() NodeSchema {
+	return NodeSchema{
+		Config: &Schema{Type: "object", Properties: map[string]*Schema{
+			"waitSeconds": {Type: "integer", Minimum: atLeast(0)},
+		}},
+	}
+}
+
+// HasSideEffects: replays skip the wait.
+func (e *WaitExecutor) HasSideEffects() bool { return true }
@@ -1,11 +1,11 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
-  wapp "github.com/Davanesh/auto-orchestrator/internal/executors"
-
+	wapp "github.com/Davanesh/auto-orchestrator/internal/executors"
 )
 
 func init() {
@@ -14,7 +14,7 @@ func init() {
 
 type WhatsAppSendExecutor struct{}
 
-func (e *WhatsAppSendExecutor) Execute(n *ExecNode, g *ExecGraph) (string, error) {
+func (e *WhatsAppSendExecutor) Execute(ctx context.Context, n *ExecNode, g *ExecGraph) (string, error) {
 	n.Status = "running"
 
 	to := fmt.Sprintf("%v", n.Data["to"])
@@ -23,8 +23,13 @@ func (e *WhatsAppSendExecutor) Execute(n *ExecNode, g *ExecGraph) (string, error
 		return "", errors.New("missing 'to' in whatsapp_send node")
 	}
 
+	// "message" is the configured text, usually a template such as
+	// {{ nodes.ai1.output }}.
 	body := ""
-	if v, ok := n.Data["output"]; ok {
+	if v, ok := n.Data["message"]; ok && v != nil {
+		body = fmt.Sprintf("%v", v)
+	}
+	if v, ok := n.Data["output"]; ok && body == "" {
 		body = fmt.Sprintf("%v", v)
 	}
 	if body == "" {
@@ -37,7 +42,7 @@ func (e *WhatsAppSendExecutor) Execute(n *ExecNode, g *ExecGraph) (string, error
 		body = "(empty message)"
 	}
 
-	_, err := wapp.ExecuteWhatsAppSendNode(to, "static", "", "", body)
+	_, err := wapp.ExecuteWhatsAppSendNode(ctx, to, "static", "", "", body)
 	if err != nil {
 		n.Status = "failed"
 		return "", err
@@ -46,3 +51,18 @@ func (e *WhatsAppSendExecutor) Execute(n *ExecNode, g *ExecGraph) (string, error
 	n.Status = "done"
 	return "", nil
 }
+
+func (e *WhatsAppSendExecutor) Schema() NodeSchema {
+	return NodeSchema{
+		Config: &Schema{Type: "object", Required: []string{"to"}, Properties: map[string]*Schema{
+			"to": {Type: "string", MinLength: 1, Description: "recipient, e.g. whatsapp:+15551234567"},
+		}},
+		Inputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"message": {Type: "string"},
+			"input":   {Type: "string"},
+		}},
+	}
+}
+
+// HasSideEffects: a replay must not message the contact again.
+func (e *WhatsAppSendExecutor) HasSideEffects() bool { return true }
@@ -1,7 +1,9 @@
 package services
 
 import (
+	"context"
 	"fmt"
+
 	wapp "github.com/Davanesh/auto-orchestrator/internal/executors"
 )
 
@@ -11,7 +13,7 @@ func init() {
 
 type WhatsAppStaticReplyExecutor struct{}
 
-func (e *WhatsAppStaticReplyExecutor) Execute(n *ExecNode, g *ExecGraph) (string, error) {
+func (e *WhatsAppStaticReplyExecutor) Execute(ctx context.Context, n *ExecNode, g *ExecGraph) (string, error) {
 	n.Status = "running"
 
 	input := fmt.Sprintf("%v", n.Data["input"])
@@ -28,3 +30,21 @@ func (e *WhatsAppStaticReplyExecutor) Execute(n *ExecNode, g *ExecGraph) (string
 	n.Status = "done"
 	return "", nil
 }
+
+func (e *WhatsAppStaticReplyExecutor) Schema() NodeSchema {
+	return NodeSchema{
+		Config: &Schema{Type: "object", Properties: map[string]*Schema{
+			"match_regex":    {Type: "string"},
+			"reply_template": {Type: "string", Description: "${1}, ${2}... insert the regex captures"},
+		}},
+		Inputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"input": {Type: "string"},
+		}},
+		Outputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"output": {Type: "string"},
+		}},
+	}
+}
+
+// HasSideEffects: replays do not send the reply again.
+func (e *WhatsAppStaticReplyExecutor) HasSideEffects() bool { return true }
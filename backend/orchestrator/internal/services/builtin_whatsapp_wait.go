@@ -1,9 +1,9 @@
 package services
 
 import (
-	"strconv"
-	wapp "github.com/Davanesh/auto-orchestrator/internal/executors"
+	"context"
 
+	wapp "github.com/Davanesh/auto-orchestrator/internal/executors"
 )
 
 func init() {
@@ -12,22 +12,12 @@ func init() {
 
 type WhatsAppWaitExecutor struct{}
 
-func (e *WhatsAppWaitExecutor) Execute(n *ExecNode, g *ExecGraph) (string, error) {
+// Execute blocks until a reply arrives. The wait is bounded by the node's
+// timeoutSeconds, which the engine applies to ctx.
+func (e *WhatsAppWaitExecutor) Execute(ctx context.Context, n *ExecNode, g *ExecGraph) (string, error) {
 	n.Status = "running"
 
-	timeout := 0
-	if v, ok := n.Data["timeoutSeconds"]; ok {
-		switch t := v.(type) {
-		case float64:
-			timeout = int(t)
-		case int:
-			timeout = t
-		case string:
-			timeout, _ = strconv.Atoi(t)
-		}
-	}
-
-	msg, err := wapp.WaitForWhatsAppMessage(g.RunID, n.ID, timeout)
+	msg, err := wapp.WaitForWhatsAppMessage(ctx, g.RunID, n.ID)
 	if err != nil {
 		n.Status = "failed"
 		return "", err
@@ -41,3 +31,14 @@ func (e *WhatsAppWaitExecutor) Execute(n *ExecNode, g *ExecGraph) (string, error
 	n.Status = "done"
 	return "", nil
 }
+
+func (e *WhatsAppWaitExecutor) Schema() NodeSchema {
+	return NodeSchema{
+		Outputs: &Schema{Type: "object", Properties: map[string]*Schema{
+			"input": {Type: "string", Description: "the reply"},
+		}},
+	}
+}
+
+// HasSideEffects: replays use the recorded reply instead of waiting for a new one.
+func (e *WhatsAppWaitExecutor) HasSideEffects() bool { return true }
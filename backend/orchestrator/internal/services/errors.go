@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// Error classes used by retry policies to decide what is worth retrying.
+const (
+	ErrorClassTimeout     = "timeout"      // node or request deadline exceeded
+	ErrorClassNetwork     = "network"      // connection refused, DNS, reset...
+	ErrorClassHTTP5xx     = "http_5xx"     // upstream answered with a server error
+	ErrorClassHTTP4xx     = "http_4xx"     // upstream rejected the request
+	ErrorClassRateLimited = "rate_limited" // upstream answered 429
+	ErrorClassConfig      = "config"       // the node is misconfigured
+	ErrorClassError       = "error"        // anything else
+)
+
+// NodeError attaches an error class to an executor error.
+type NodeError struct {
+	Class string
+	Err   error
+}
+
+func (e *NodeError) Error() string { return e.Err.Error() }
+func (e *NodeError) Unwrap() error { return e.Err }
+
+// Classified wraps err with an explicit error class.
+func Classified(class string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &NodeError{Class: class, Err: err}
+}
+
+// httpStatusError is implemented by errors that carry an upstream HTTP
+// status, such as the Twilio and Ollama clients' errors.
+type httpStatusError interface {
+	HTTPStatus() int
+}
+
+// ClassifyError returns the error class of err, or "" for a nil error.
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var ne *NodeError
+	if errors.As(err, &ne) && ne.Class != "" {
+		return ne.Class
+	}
+
+	var se httpStatusError
+	if errors.As(err, &se) {
+		switch code := se.HTTPStatus(); {
+		case code == 429:
+			return ErrorClassRateLimited
+		case code >= 500:
+			return ErrorClassHTTP5xx
+		case code >= 400:
+			return ErrorClassHTTP4xx
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassTimeout
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ErrorClassTimeout
+		}
+		return ErrorClassNetwork
+	}
+
+	return ErrorClassError
+}
@@ -1,10 +1,18 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
+	"time"
+
+	"github.com/Davanesh/auto-orchestrator/internal/models"
 )
 
+// DefaultMaxConcurrency is the worker pool size used when a run does not set
+// ExecGraph.MaxConcurrency.
+const DefaultMaxConcurrency = 4
 
 /*
 ----------------------------------------------------
@@ -12,7 +20,31 @@ import (
 ----------------------------------------------------
 */
 
-func RunWorkflow(g *ExecGraph) error {
+// RunWorkflow executes the graph starting at g.Start.
+//
+// Nodes are scheduled by in-degree: a node becomes ready once every incoming
+// edge has been resolved, and independent branches run side by side on a
+// worker pool bounded by g.MaxConcurrency. A node runs when at least one of its
+// incoming edges was taken; if none was (e.g. the branch a decision did not
+// pick) it is marked "skipped" and the skip propagates downstream.
+//
+// Cancelling ctx stops every in-flight node; a node failure cancels the rest
+// of the run the same way. Nodes interrupted like this end up "cancelled",
+// and so do the nodes a cancelled run never got to.
+//
+// A paused run (see ExecGraph.Pause) lets running nodes finish but starts no
+// new ones until it is resumed.
+//
+// Join nodes in "any" or "quorum" mode run as soon as enough branches have
+// arrived; the branches that only feed such a join are then cancelled.
+//
+// A failing node with error edges (ErrorNext) does not fail the run: the
+// error is handed to the handler nodes on those edges and the run goes on.
+//
+// Nodes that are already "done" (a run restored from a checkpoint) are not
+// executed again: their recorded Branch is replayed and scheduling continues
+// from there.
+func RunWorkflow(ctx context.Context, g *ExecGraph) error {
 	if g == nil {
 		return errors.New("nil graph")
 	}
@@ -20,48 +52,506 @@ func RunWorkflow(g *ExecGraph) error {
 		return errors.New("no start node defined")
 	}
 
-	log.Println("🚀 Starting workflow execution (new engine)")
-	current := g.Start
+	log.Printf("🚀 Starting workflow execution (run=%s)", g.RunID)
 
-	for {
-		n, ok := g.Nodes[current]
-		if !ok {
-			return errors.New("node not found: " + current)
+	if err := runRegion(ctx, g, []string{g.Start}, ""); err != nil {
+		if ctx.Err() != nil {
+			g.cancelPending()
+			log.Println("⛔ Workflow cancelled")
 		}
+		return err
+	}
 
-		var nextOverride string
-		var err error
+	log.Println("🏁 Workflow complete!")
+	return nil
+}
 
-		executor, err := GetExecutor(n.Type)
-		if err != nil {
-				return errors.New("no executor for node type: " + n.Type)
+// runRegion schedules the part of the graph reachable from entries. Edges
+// into stop are not followed; loop nodes use this to run their body with the
+// back edges to the loop node ending an iteration.
+func runRegion(ctx context.Context, g *ExecGraph, entries []string, stop string) error {
+	region, err := g.reachableFrom(entries, stop)
+	if err != nil {
+		return err
+	}
+
+	// Reject cycles up front: an in-degree scheduler would deadlock on them.
+	if _, err := TopologicalSort(g.Graph(region)); err != nil {
+		return err
+	}
+
+	limit := g.MaxConcurrency
+	if limit <= 0 {
+		limit = DefaultMaxConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s := &scheduler{
+		g:         g,
+		region:    region,
+		stop:      stop,
+		cancel:    cancel,
+		remaining: map[string]int{},
+		arrived:   map[string][]string{},
+		fired:     map[string]bool{},
+		pruned:    map[string]bool{},
+		running:   map[string]context.CancelFunc{},
+	}
+	for id := range region {
+		for _, child := range g.Nodes[id].edges() {
+			if child != stop {
+				s.remaining[child]++
+			}
+		}
+	}
+
+	return s.run(ctx, limit, entries)
+}
+
+// scheduler holds the bookkeeping for a single region of a run. It is only
+// touched from the coordinating goroutine; executors run on workers and report
+// back through the results channel.
+type scheduler struct {
+	g      *ExecGraph
+	region map[string]bool
+	stop   string             // edges into this node are not followed
+	cancel context.CancelFunc // cancels the whole region
+	err    error              // first failure not caught by an error edge
+
+	remaining map[string]int                // nodeID -> incoming edges not yet resolved
+	arrived   map[string][]string           // nodeID -> sources of taken incoming edges, in order
+	fired     map[string]bool               // join satisfied before all its edges settled
+	pruned    map[string]bool               // branch no longer needed by a satisfied join
+	running   map[string]context.CancelFunc // in-flight nodes
+	ready     []string
+}
+
+type nodeResult struct {
+	id   string
+	node *ExecNode
+	next string
+	err  error
+}
+
+func (s *scheduler) run(ctx context.Context, limit int, entries []string) error {
+	results := make(chan nodeResult)
+
+	s.ready = append(s.ready, entries...)
+
+	for len(s.ready) > 0 || len(s.running) > 0 {
+		// While the run is paused nothing new is started; the nodes that
+		// would run next are shown as "paused".
+		gate := s.g.pauseGate()
+		if gate != nil {
+			s.hold()
+		}
+
+		// Dispatch as many ready nodes as the pool allows. Once a node has
+		// failed nothing new is started; in-flight nodes are drained.
+		for gate == nil && s.err == nil && ctx.Err() == nil && len(s.ready) > 0 && len(s.running) < limit {
+			id := s.ready[0]
+			s.ready = s.ready[1:]
+
+			n := s.g.Nodes[id]
+			if s.pruned[id] {
+				s.skip(n)
+				continue
+			}
+
+			// Settled before a restart: replay the outcome only.
+			if n.Status == "done" {
+				s.follow(n)
+				continue
+			}
+			if n.Status == "failed" && len(n.ErrorNext) > 0 {
+				s.catch(n)
+				continue
+			}
+
+			executor, err := GetExecutor(n.Type)
+			if err != nil {
+				err = Classified(ErrorClassConfig, errors.New("no executor for node type: "+n.Type))
+				if !s.fail(n, n, err) {
+					s.failRun(err)
+				}
+				continue
+			}
+
+			s.g.setStatus(n, "running")
+			work := n.clone()
+			nctx, ncancel := context.WithCancel(ctx)
+			s.running[id] = ncancel
+
+			go func(id string, work *ExecNode) {
+				done, next, err := executeWithRetry(nctx, executor, work, s.g)
+				results <- nodeResult{id: id, node: done, next: next, err: err}
+			}(id, work)
+		}
+
+		if len(s.running) == 0 && (len(s.ready) == 0 || s.err != nil || ctx.Err() != nil) {
+			break
 		}
 
-		nextOverride, err = executor.Execute(n, g)
+		// With nothing in flight only a resume or a cancellation can move a
+		// paused run forward.
+		var cancelled <-chan struct{}
+		if len(s.running) == 0 {
+			cancelled = ctx.Done()
+		}
 
+		select {
+		case res := <-results:
+			s.handle(ctx, res)
+		case <-gate:
+		case <-cancelled:
+		}
+	}
+
+	if s.err != nil {
+		return s.err
+	}
+	return ctx.Err()
+}
+
+// handle stores the outcome of a finished worker and settles its edges.
+func (s *scheduler) handle(ctx context.Context, res nodeResult) {
+	s.running[res.id]()
+	delete(s.running, res.id)
+
+	n := s.g.Nodes[res.id]
+	if res.err != nil {
+		switch {
+		case s.pruned[res.id]:
+			// Cancelled because the join it fed no longer needs it.
+			s.g.finish(n, res.node, "cancelled", res.err)
+			log.Printf("⛔ Branch cancelled: %s", n.ID)
+			s.settle(n)
+		case ctx.Err() != nil:
+			// Interrupted by a cancelled run, not a failure of its own.
+			s.g.finish(n, res.node, "cancelled", res.err)
+			log.Printf("⛔ Node cancelled: %s", n.ID)
+		default:
+			if !s.fail(n, res.node, res.err) {
+				s.failRun(res.err)
+			}
+		}
+		return
+	}
+
+	if res.next != "" && !contains(n.Next, res.next) {
+		err := fmt.Errorf("node %s chose branch %s which is not one of its connections", n.ID, res.next)
+		if !s.fail(n, res.node, err) {
+			s.failRun(err)
+		}
+		return
+	}
+
+	for _, fe := range validateOutputs(res.node) {
+		log.Printf("⚠️ Output does not match schema: %v", fe)
+	}
+
+	res.node.Branch = res.next
+	s.g.finish(n, res.node, "done", nil)
+	s.follow(n)
+}
+
+// hold marks the queued nodes "paused" while the run is paused.
+func (s *scheduler) hold() {
+	for _, id := range s.ready {
+		n := s.g.Nodes[id]
+		if n.Status == "" || n.Status == "pending" {
+			s.g.setStatus(n, "paused")
+		}
+	}
+}
+
+// failRun records the first uncaught failure and cancels everything still
+// running in the region.
+func (s *scheduler) failRun(err error) {
+	if s.err == nil {
+		s.err = err
+		s.cancel()
+	}
+}
+
+// executeWithRetry runs the node's attempts according to its retry policy.
+// Every attempt works on a fresh copy of n, so a failed attempt cannot leak
+// partial output into the next one, and resolves the copy's templates
+// against the current state of the run. It returns the copy of the last
+// attempt.
+func executeWithRetry(ctx context.Context, executor NodeExecutor, n *ExecNode, g *ExecGraph) (*ExecNode, string, error) {
+	policy, err := retryPolicyFromData(n.Data)
+	if err != nil {
+		return n, "", Classified(ErrorClassConfig, fmt.Errorf("node %s: %w", n.ID, err))
+	}
+
+	started := n.StartedAt
+	seq := g.nextSeq(n.ID)
+	for attempt := 1; ; attempt++ {
+		work := n.clone()
+		work.Attempts = attempt
+
+		rewritten, err := resolveNode(ctx, work, g)
 		if err != nil {
-			n.Status = "failed"
-			log.Printf("❌ Node failed: %s (%v)", n.ID, err)
-			return err
+			g.attempt(work, attempt, started, err)
+			return work, "", err
 		}
 
-		// If decision returned a target → follow it
-		if nextOverride != "" {
-			current = nextOverride
-			continue
+		input := copyData(work.Data)
+		replayed := g.Replay != nil && hasSideEffects(executor)
+		var next string
+		if replayed {
+			next, err = g.Replay.replayNode(work, seq)
+		} else {
+			next, err = executeNode(ctx, executor, work, g, started)
+		}
+		g.record(models.Recording{
+			NodeID:     n.ID,
+			Type:       n.Type,
+			Seq:        seq,
+			Attempt:    attempt,
+			Input:      input,
+			Output:     copyData(work.Data),
+			Next:       next,
+			Error:      errString(err),
+			ErrorClass: ClassifyError(err),
+			Replayed:   replayed,
+			Timestamp:  time.Now(),
+		})
+		restoreTemplates(work, rewritten)
+		g.attempt(work, attempt, started, err)
+		if err == nil {
+			return work, next, nil
 		}
 
-		// No more next nodes → workflow ends
-		if len(n.Next) == 0 {
-			log.Println("🏁 Workflow complete!")
-			return nil
+		class := ClassifyError(err)
+		if attempt >= policy.MaxAttempts || !policy.Retryable(class) || ctx.Err() != nil {
+			return work, "", err
 		}
 
-		// Non-decision nodes should only have 1 next
-		if len(n.Next) > 1 {
-			return errors.New("node has multiple next branches but is not a decision: " + n.ID)
+		delay := policy.Delay(attempt + 1)
+		log.Printf("🔁 Node %s failed (%s: %v), retrying in %s (attempt %d/%d)",
+			n.ID, class, err, delay, attempt+1, policy.MaxAttempts)
+		if err := sleepCtx(ctx, delay); err != nil {
+			return work, "", err
 		}
+		started = time.Now()
+	}
+}
 
-		current = n.Next[0]
+// executeNode runs a single attempt under the node's own deadline. A node
+// may set Data["timeoutSeconds"]; zero or missing means no per-attempt limit.
+// The deadline counts from started, so a node resumed after a restart only
+// gets the time it had left.
+func executeNode(ctx context.Context, executor NodeExecutor, n *ExecNode, g *ExecGraph, started time.Time) (string, error) {
+	secs := dataInt(n.Data, "timeoutSeconds")
+	if secs <= 0 {
+		return executor.Execute(ctx, n, g)
+	}
+
+	nctx, cancel := context.WithDeadline(ctx, started.Add(time.Duration(secs)*time.Second))
+	defer cancel()
+
+	next, err := executor.Execute(nctx, n, g)
+	if err != nil && ctx.Err() == nil && errors.Is(nctx.Err(), context.DeadlineExceeded) {
+		return "", Classified(ErrorClassTimeout, fmt.Errorf("node %s timed out after %ds", n.ID, secs))
+	}
+	return next, err
+}
+
+// fail marks the node failed. It reports whether the failure was caught by
+// an error edge; if not, the caller fails the run.
+func (s *scheduler) fail(n *ExecNode, work *ExecNode, err error) bool {
+	s.g.finish(n, work, "failed", err)
+	if len(n.ErrorNext) == 0 {
+		log.Printf("❌ Node failed: %s (%v)", n.ID, err)
+		return false
+	}
+
+	log.Printf("🛟 Node failed: %s (%v), following error edge", n.ID, err)
+	s.catch(n)
+	return true
+}
+
+// catch settles the edges of a failed node that has error edges: the
+// handlers receive the failure in Data["error"] and the normal edges are
+// not taken.
+func (s *scheduler) catch(n *ExecNode) {
+	details := map[string]interface{}{
+		"nodeId":   n.ID,
+		"label":    n.Label,
+		"type":     n.Type,
+		"message":  n.Error,
+		"class":    n.ErrorClass,
+		"attempts": n.Attempts,
+	}
+
+	s.g.mu.Lock()
+	for _, id := range n.ErrorNext {
+		if h, ok := s.g.Nodes[id]; ok {
+			if h.Data == nil {
+				h.Data = map[string]interface{}{}
+			}
+			h.Data["error"] = details
+		}
+	}
+	s.g.mu.Unlock()
+
+	for _, child := range n.Next {
+		s.resolve(n.ID, child, false)
+	}
+	for _, child := range n.ErrorNext {
+		s.resolve(n.ID, child, true)
+	}
+}
+
+// follow settles the outgoing edges of a completed node. A non-empty Branch
+// selects a single edge (decision); otherwise every outgoing edge is taken
+// and the branches fan out. Error edges are not taken.
+func (s *scheduler) follow(n *ExecNode) {
+	for _, child := range n.Next {
+		s.resolve(n.ID, child, n.Branch == "" || child == n.Branch)
+	}
+	for _, child := range n.ErrorNext {
+		s.resolve(n.ID, child, false)
+	}
+}
+
+// settle resolves every outgoing edge of n as not taken.
+func (s *scheduler) settle(n *ExecNode) {
+	for _, child := range n.edges() {
+		s.resolve(n.ID, child, false)
+	}
+}
+
+// skip marks a node that will not run and pushes the skip downstream.
+func (s *scheduler) skip(n *ExecNode) {
+	s.g.setStatus(n, "skipped")
+	s.settle(n)
+}
+
+// resolve marks the edge from -> id as settled. When the last edge settles
+// the node is either queued (some edge was taken) or skipped, in which case
+// the skip is pushed to its own children. A join node in "any" or "quorum"
+// mode is queued as soon as enough branches have arrived.
+func (s *scheduler) resolve(from, id string, taken bool) {
+	if id == s.stop {
+		return
+	}
+	if taken {
+		s.arrived[id] = append(s.arrived[id], from)
+	}
+	s.remaining[id]--
+	if s.fired[id] {
+		return
+	}
+
+	n := s.g.Nodes[id]
+	need := 0
+	if n.Type == "join" {
+		need, _ = joinRequirement(n)
+	}
+
+	arrived := len(s.arrived[id])
+	if s.remaining[id] > 0 {
+		if need > 0 && arrived >= need && !s.pruned[id] {
+			s.fired[id] = true
+			s.queueJoin(n)
+			s.prune(id)
+		}
+		return
+	}
+
+	if arrived == 0 || s.pruned[id] {
+		s.skip(n)
+		return
+	}
+	if need > arrived {
+		err := Classified(ErrorClassConfig, fmt.Errorf("join %s needed %d branches, only %d arrived", id, need, arrived))
+		if !s.fail(n, n, err) {
+			s.failRun(err)
+		}
+		return
+	}
+
+	if n.Type == "join" {
+		s.queueJoin(n)
+		return
+	}
+	s.ready = append(s.ready, id)
+}
+
+// queueJoin hands the arrived branches' results to the join node and
+// queues it.
+func (s *scheduler) queueJoin(n *ExecNode) {
+	arrivals := append([]string(nil), s.arrived[n.ID]...)
+
+	s.g.mu.Lock()
+	inputs := map[string]interface{}{}
+	for _, from := range arrivals {
+		if src, ok := s.g.Nodes[from]; ok {
+			inputs[from], _ = src.result()
+		}
+	}
+	n.Data["arrivals"] = arrivals
+	n.Data["inputs"] = inputs
+	s.g.mu.Unlock()
+
+	log.Printf("🔀 Join %s ready with %d branch(es)", n.ID, len(arrivals))
+	s.ready = append(s.ready, n.ID)
+}
+
+// prune cancels the branches that only feed a join which has already fired:
+// every unfinished node whose outgoing paths all lead into the join.
+func (s *scheduler) prune(join string) {
+	memo := map[string]bool{}
+	var feedsOnly func(id string) bool
+	feedsOnly = func(id string) bool {
+		if v, ok := memo[id]; ok {
+			return v
+		}
+		memo[id] = false
+		edges := s.g.Nodes[id].edges()
+		if len(edges) == 0 {
+			return false
+		}
+		for _, c := range edges {
+			if c == join {
+				continue
+			}
+			if c == s.stop || !s.region[c] || !feedsOnly(c) {
+				return false
+			}
+		}
+		memo[id] = true
+		return true
+	}
+
+	for id := range s.region {
+		if id == join {
+			continue
+		}
+		switch s.g.Nodes[id].Status {
+		case "done", "failed", "skipped", "cancelled":
+			continue
+		}
+		if !feedsOnly(id) {
+			continue
+		}
+		s.pruned[id] = true
+		if cancel, ok := s.running[id]; ok {
+			cancel()
+		}
+	}
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
 	}
+	return false
 }
@@ -2,15 +2,31 @@ package services
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	wapp "github.com/Davanesh/auto-orchestrator/internal/executors"
 )
 
+// LambdaEndpointEnv overrides the Lambda endpoint for every lambda node,
+// e.g. http://localhost:3001 for a local emulator.
+const LambdaEndpointEnv = "LAMBDA_ENDPOINT_URL"
+
+// LambdaEndpointsEnv lists, comma-separated, the endpoints a node's
+// endpointUrl may name besides LAMBDA_ENDPOINT_URL. Requests are signed
+// with the server's AWS credentials, so workflows cannot pick arbitrary
+// endpoints.
+const LambdaEndpointsEnv = "LAMBDA_ENDPOINT_URLS"
+
 // LambdaInvoker holds the AWS Lambda client
 type LambdaInvoker struct {
 	client *lambda.Client
@@ -18,48 +34,100 @@ type LambdaInvoker struct {
 
 // NewLambdaInvoker initializes and returns a LambdaInvoker.
 // It loads credentials from the environment/`~/.aws/credentials` (aws configure).
-func NewLambdaInvoker() (*LambdaInvoker, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// A non-empty endpoint replaces the AWS endpoint; emulators usually need no
+// real credentials, and the region defaults to us-east-1 for them.
+func NewLambdaInvoker(ctx context.Context, endpoint string) (*LambdaInvoker, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	cfg, err := config.LoadDefaultConfig(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
+	if endpoint != "" && cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
 
 	return &LambdaInvoker{
-		client: lambda.NewFromConfig(cfg),
+		client: lambda.NewFromConfig(cfg, func(o *lambda.Options) {
+			if endpoint != "" {
+				o.BaseEndpoint = aws.String(endpoint)
+			}
+		}),
 	}, nil
 }
 
-// Invoke invokes a Lambda function by name with a payload (any serializable object).
-// Returns the raw payload bytes and the function error (if any).
-func (li *LambdaInvoker) Invoke(functionName string, payload interface{}) ([]byte, error) {
-	// Marshal the payload to JSON
-	body, err := json.Marshal(payload)
+// LambdaResult is the outcome of an invocation. Payload is empty for
+// asynchronous (Event) invocations.
+type LambdaResult struct {
+	StatusCode int
+	Payload    []byte
+}
+
+// Invoke invokes a Lambda function by name with a JSON payload.
+// invocationType is RequestResponse (wait for the result) or Event (queue
+// the call and return). A function error is returned together with its
+// payload, which holds the error details.
+func (li *LambdaInvoker) Invoke(ctx context.Context, functionName string, payload []byte, invocationType types.InvocationType) (*LambdaResult, error) {
+	out, err := li.client.Invoke(ctx, &lambda.InvokeInput{
+		FunctionName:   aws.String(functionName),
+		Payload:        payload,
+		InvocationType: invocationType,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		// Surface the HTTP status so retry policies can classify it.
+		var re interface{ HTTPStatusCode() int }
+		if errors.As(err, &re) && re.HTTPStatusCode() > 0 {
+			return nil, wapp.NewHTTPError("lambda", re.HTTPStatusCode(), err.Error())
+		}
+		return nil, fmt.Errorf("lambda invoke error: %w", err)
 	}
 
-	input := &lambda.InvokeInput{
-		FunctionName: &functionName,
-		Payload:      body,
-		// You can set InvocationType: types.InvocationTypeRequestResponse for sync (default)
-		InvocationType: types.InvocationTypeRequestResponse,
+	res := &LambdaResult{StatusCode: int(out.StatusCode), Payload: out.Payload}
+	if out.FunctionError != nil && *out.FunctionError != "" {
+		return res, fmt.Errorf("lambda function error (%s): %s", *out.FunctionError, out.Payload)
 	}
+	return res, nil
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
+// lambdaInvokers caches one invoker per endpoint; loading the AWS config is
+// too slow to repeat for every node.
+var lambdaInvokers = struct {
+	sync.Mutex
+	m map[string]*LambdaInvoker
+}{m: map[string]*LambdaInvoker{}}
 
-	out, err := li.client.Invoke(ctx, input)
-	if err != nil {
-		return nil, fmt.Errorf("lambda invoke error: %w", err)
+// lambdaInvoker returns the invoker for endpoint ("" = AWS, or the
+// LAMBDA_ENDPOINT_URL override).
+func lambdaInvoker(ctx context.Context, endpoint string) (*LambdaInvoker, error) {
+	if endpoint == "" {
+		endpoint = os.Getenv(LambdaEndpointEnv)
+	} else if err := checkLambdaEndpoint(endpoint); err != nil {
+		return nil, err
 	}
 
-	// If the Lambda returned an error (FunctionError set), include that in returned error
-	if out.FunctionError != nil && *out.FunctionError != "" {
-		return out.Payload, fmt.Errorf("lambda function error: %s", *out.FunctionError)
+	lambdaInvokers.Lock()
+	defer lambdaInvokers.Unlock()
+	if li, ok := lambdaInvokers.m[endpoint]; ok {
+		return li, nil
 	}
+	li, err := NewLambdaInvoker(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	lambdaInvokers.m[endpoint] = li
+	return li, nil
+}
 
-	return out.Payload, nil
+// checkLambdaEndpoint rejects a node endpoint that is neither
+// LAMBDA_ENDPOINT_URL nor listed in LAMBDA_ENDPOINT_URLS.
+func checkLambdaEndpoint(endpoint string) error {
+	allowed := strings.Split(os.Getenv(LambdaEndpointsEnv), ",")
+	allowed = append(allowed, os.Getenv(LambdaEndpointEnv))
+	for _, a := range allowed {
+		if a = strings.TrimSpace(a); a != "" && a == endpoint {
+			return nil
+		}
+	}
+	return fmt.Errorf("endpoint %q is not allowed; list it in %s", endpoint, LambdaEndpointsEnv)
 }
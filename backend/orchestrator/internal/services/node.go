@@ -1,5 +1,18 @@
 package services
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Davanesh/auto-orchestrator/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
 // node.go
 // Core node types & small helpers
 
@@ -18,11 +31,372 @@ type ExecNode struct {
 	Data   map[string]interface{}
 	Status string
 	Next   []string // adjacency
+
+	// ErrorNext are the targets of error/catch edges, taken only when the
+	// node fails.
+	ErrorNext []string
+
+	// Body are the entry nodes of a loop's body. They are run by the loop
+	// executor, not by the scheduler of the region the loop belongs to.
+	Body []string
+
+	// LambdaName is the function a lambda node invokes when its data names
+	// none.
+	LambdaName string
+
+	// Cases maps the targets of a switch's outgoing edges to the case names
+	// on those edges ("default" for the default edge).
+	Cases map[string]string
+
+	// Filled in by the engine as the node moves through its lifecycle.
+	// Branch is the edge a decision picked ("" means all edges were taken).
+	Branch     string
+	Error      string
+	ErrorClass string
+	Attempts   int
+	StartedAt  time.Time
+	FinishedAt time.Time
 }
 
 // ExecGraph already used by your api
 type ExecGraph struct {
-	Nodes map[string]*ExecNode
-	Start string
-	RunID string
+	Nodes      map[string]*ExecNode
+	Start      string
+	RunID      string
+	WorkflowID string
+
+	// ParentRunID, ParentNodeID and Depth link a subworkflow's child run to
+	// its parent. Top-level runs have depth 0.
+	ParentRunID  string
+	ParentNodeID string
+	Depth        int
+
+	// MaxConcurrency bounds how many nodes of this run execute at once.
+	// Zero means DefaultMaxConcurrency.
+	MaxConcurrency int
+
+	// Input is the JSON payload the run was started with.
+	Input map[string]interface{}
+
+	// OutputDefs are the workflow's named outputs, resolved by Outputs.
+	OutputDefs []models.WorkflowOutput
+
+	// OnNodeUpdate, if set, is called after every node status change. It runs
+	// on the engine's coordinating goroutine; nodes inside a loop body are
+	// reported from the loop's goroutine.
+	OnNodeUpdate func(n *ExecNode)
+
+	// OnAttempt, if set, is called after every executor attempt with its
+	// error (nil on success). Attempts of parallel nodes call it concurrently.
+	OnAttempt func(n *ExecNode, attempt int, started time.Time, err error)
+
+	// OnRecord, if set, is called with the data every executor attempt ran
+	// with and produced, so the run can be replayed. Like OnAttempt it is
+	// called concurrently.
+	OnRecord func(rec models.Recording)
+
+	// Replay, if set, feeds the recorded results of an earlier run to nodes
+	// with side effects instead of executing them.
+	Replay *Replay
+
+	// seqs counts each node's executions for recordings.
+	seqs map[string]int
+
+	// paused is non-nil while the run is paused and is closed by Resume.
+	paused chan struct{}
+
+	// mu guards node Status/Data while branches run in parallel.
+	mu sync.RWMutex
+}
+
+// Pause stops the run from starting new nodes; nodes already running are
+// allowed to finish. It reports false if the run was already paused.
+func (g *ExecGraph) Pause() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.paused != nil {
+		return false
+	}
+	g.paused = make(chan struct{})
+	return true
+}
+
+// Resume lets a paused run schedule nodes again. It reports false if the run
+// was not paused.
+func (g *ExecGraph) Resume() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.paused == nil {
+		return false
+	}
+	close(g.paused)
+	g.paused = nil
+	return true
+}
+
+// Paused reports whether the run is paused.
+func (g *ExecGraph) Paused() bool {
+	return g.pauseGate() != nil
+}
+
+// cancelPending marks the nodes a cancelled run never started "cancelled".
+func (g *ExecGraph) cancelPending() {
+	for _, n := range g.Nodes {
+		g.mu.RLock()
+		status := n.Status
+		g.mu.RUnlock()
+		if status == "" || status == "pending" || status == "paused" {
+			g.setStatus(n, "cancelled")
+		}
+	}
+}
+
+// pauseGate returns a channel closed on Resume, or nil if the run is not
+// paused.
+func (g *ExecGraph) pauseGate() chan struct{} {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.paused
+}
+
+// clone returns a copy of the node that an executor can mutate freely on a
+// worker goroutine. The engine copies the results back with finish.
+func (n *ExecNode) clone() *ExecNode {
+	c := *n
+	c.Data = make(map[string]interface{}, len(n.Data))
+	for k, v := range n.Data {
+		c.Data[k] = v
+	}
+	c.Next = append([]string(nil), n.Next...)
+	c.ErrorNext = append([]string(nil), n.ErrorNext...)
+	c.Body = append([]string(nil), n.Body...)
+	return &c
+}
+
+// edges returns the targets of all outgoing edges, normal and error.
+func (n *ExecNode) edges() []string {
+	if len(n.ErrorNext) == 0 {
+		return n.Next
+	}
+	return append(append([]string(nil), n.Next...), n.ErrorNext...)
+}
+
+func (g *ExecGraph) setStatus(n *ExecNode, status string) {
+	g.mu.Lock()
+	n.Status = status
+	if status == "running" && n.StartedAt.IsZero() {
+		n.StartedAt = time.Now()
+	}
+	g.mu.Unlock()
+	g.notify(n)
+}
+
+// finish stores the executor's copy of the node back into the graph.
+func (g *ExecGraph) finish(n *ExecNode, work *ExecNode, status string, err error) {
+	g.mu.Lock()
+	n.Data = work.Data
+	n.Branch = work.Branch
+	n.Attempts = work.Attempts
+	n.Status = status
+	n.FinishedAt = time.Now()
+	n.Error = ""
+	n.ErrorClass = ""
+	if err != nil {
+		n.Error = err.Error()
+		n.ErrorClass = ClassifyError(err)
+	}
+	g.mu.Unlock()
+	g.notify(n)
+}
+
+func (g *ExecGraph) attempt(n *ExecNode, attempt int, started time.Time, err error) {
+	if g.OnAttempt != nil {
+		g.OnAttempt(n, attempt, started, err)
+	}
+}
+
+func (g *ExecGraph) notify(n *ExecNode) {
+	if g.OnNodeUpdate != nil {
+		g.OnNodeUpdate(n)
+	}
+}
+
+// reachableFrom returns the set of node IDs reachable from entries without
+// passing through stop, and checks that every edge on the way points at a
+// known node.
+func (g *ExecGraph) reachableFrom(entries []string, stop string) (map[string]bool, error) {
+	seen := map[string]bool{}
+	stack := append([]string(nil), entries...)
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[id] || id == stop {
+			continue
+		}
+		n, ok := g.Nodes[id]
+		if !ok {
+			return nil, errors.New("node not found: " + id)
+		}
+		seen[id] = true
+		stack = append(stack, n.edges()...)
+	}
+	return seen, nil
+}
+
+// Graph converts the given subset of the execution graph into the adjacency
+// form used by TopologicalSort. Edges leaving the subset are dropped.
+func (g *ExecGraph) Graph(ids map[string]bool) *Graph {
+	out := &Graph{
+		Adj:      make(map[string][]string),
+		InDegree: make(map[string]int),
+		Nodes:    make(map[string]models.Node),
+	}
+	for id := range ids {
+		n := g.Nodes[id]
+		out.Nodes[id] = models.Node{CanvasID: id, Type: n.Type, Label: n.Label}
+		if _, ok := out.InDegree[id]; !ok {
+			out.InDegree[id] = 0
+		}
+		out.Adj[id] = []string{}
+		for _, child := range n.edges() {
+			if !ids[child] {
+				continue
+			}
+			out.Adj[id] = append(out.Adj[id], child)
+			out.InDegree[child]++
+		}
+	}
+	return out
+}
+
+// caseTarget returns the target of the edge bound to the named case.
+func (n *ExecNode) caseTarget(name string) (string, bool) {
+	for _, target := range n.Next {
+		if c, ok := n.Cases[target]; ok && c == name {
+			return target, true
+		}
+	}
+	return "", false
+}
+
+// result returns what a finished node produced: its "output", or its
+// "input" for nodes such as WhatsApp waits that store the reply there.
+func (n *ExecNode) result() (interface{}, bool) {
+	if v, ok := n.Data["output"]; ok {
+		return v, true
+	}
+	v, ok := n.Data["input"]
+	return v, ok
+}
+
+// Outputs returns the results of the run. A workflow with OutputDefs
+// returns each named output resolved against the run context. Otherwise the
+// results of its end nodes are returned: every node that finished "done" and
+// has no outgoing edges, keyed by node ID.
+func (g *ExecGraph) Outputs() (map[string]interface{}, error) {
+	if len(g.OutputDefs) > 0 {
+		scope := g.TemplateContext()
+		out := map[string]interface{}{}
+		for _, o := range g.OutputDefs {
+			v, err := resolveValue(o.Value, scope)
+			if err != nil {
+				return out, fmt.Errorf("output %s: %w", o.Name, err)
+			}
+			out[o.Name] = v
+		}
+		return out, nil
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	out := map[string]interface{}{}
+	for id, n := range g.Nodes {
+		if n.Status != "done" || len(n.Next) > 0 || len(n.ErrorNext) > 0 {
+			continue
+		}
+		if v, ok := n.result(); ok {
+			out[id] = v
+		}
+	}
+	return out, nil
+}
+
+// dataInt reads an integer setting from node data. JSON numbers arrive as
+// float64 and the frontend sometimes stores them as strings.
+func dataInt(data map[string]interface{}, key string) int {
+	f, _ := toFloat(data[key])
+	return int(f)
+}
+
+// toFloat converts the number shapes found in node data (JSON, BSON and
+// strings typed into the canvas) to a float64.
+func toFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case int32:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// asMap returns v as a JSON object. Nested documents read back from Mongo
+// may come as primitive.M.
+func asMap(v interface{}) (map[string]interface{}, bool) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return t, true
+	case primitive.M:
+		return t, true
+	case primitive.D:
+		m := make(map[string]interface{}, len(t))
+		for _, e := range t {
+			m[e.Key] = e.Value
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+// asSlice returns v as a JSON array. Arrays read back from Mongo come as
+// primitive.A.
+func asSlice(v interface{}) ([]interface{}, bool) {
+	switch t := v.(type) {
+	case []interface{}:
+		return t, true
+	case primitive.A:
+		return t, true
+	case []string:
+		out := make([]interface{}, len(t))
+		for i, s := range t {
+			out[i] = s
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// sleepCtx sleeps for d or until ctx is done, whichever comes first.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
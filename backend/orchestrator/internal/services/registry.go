@@ -1,12 +1,18 @@
 package services
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 // NodeExecutor executes a node and returns next node id (or empty if engine should use Next[]).
 // For decision nodes it returns the next node id to jump to.
-// For normal nodes it returns "" meaning: engine will pick node.Next[0] (must exist).
+// For normal nodes it returns "" meaning: engine will follow every node.Next edge.
+//
+// ctx is cancelled when the run is cancelled or the node's timeoutSeconds
+// elapses; executors must return promptly once ctx is done.
 type NodeExecutor interface {
-	Execute(node *ExecNode, g *ExecGraph) (next string, err error)
+	Execute(ctx context.Context, node *ExecNode, g *ExecGraph) (next string, err error)
 }
 
 // simple registry
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+
+	"github.com/Davanesh/auto-orchestrator/internal/models"
+)
+
+// SideEffector is implemented by executors that reach outside the engine
+// (Ollama, Twilio, other runs, persistent state, the clock). When a run is
+// replayed their recorded results are used instead of executing them; every
+// other node runs again for real.
+type SideEffector interface {
+	HasSideEffects() bool
+}
+
+func hasSideEffects(executor NodeExecutor) bool {
+	se, ok := executor.(SideEffector)
+	return ok && se.HasSideEffects()
+}
+
+// Replay feeds the recordings of an earlier run to a new run of the same
+// graph (see ExecGraph.Replay).
+type Replay struct {
+	recs map[replayKey]models.Recording
+}
+
+type replayKey struct {
+	node    string
+	seq     int
+	attempt int
+}
+
+// NewReplay indexes the recordings of the run to replay.
+func NewReplay(recs []models.Recording) *Replay {
+	r := &Replay{recs: make(map[replayKey]models.Recording, len(recs))}
+	for _, rec := range recs {
+		r.recs[replayKey{rec.NodeID, rec.Seq, rec.Attempt}] = rec
+	}
+	return r
+}
+
+// replayNode stands in for executing n: it copies the recorded output into
+// n's data and returns the recorded branch and error.
+func (r *Replay) replayNode(n *ExecNode, seq int) (string, error) {
+	rec, ok := r.recs[replayKey{n.ID, seq, n.Attempts}]
+	if !ok {
+		return "", Classified(ErrorClassConfig,
+			fmt.Errorf("replay: node %s has no recording for execution %d, attempt %d", n.ID, seq, n.Attempts))
+	}
+
+	log.Printf("⏪ Replaying node %s (execution %d, attempt %d)", n.ID, seq, n.Attempts)
+	n.Data = make(map[string]interface{}, len(rec.Output))
+	for k, v := range rec.Output {
+		n.Data[k] = v
+	}
+	n.Status = "done"
+
+	if rec.Error == "" {
+		return rec.Next, nil
+	}
+	class := rec.ErrorClass
+	if class == "" {
+		class = ErrorClassError
+	}
+	return rec.Next, Classified(class, errors.New(rec.Error))
+}
+
+// nextSeq returns how many times the node has started executing in this run
+// before, and counts this execution.
+func (g *ExecGraph) nextSeq(id string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.seqs == nil {
+		g.seqs = map[string]int{}
+	}
+	seq := g.seqs[id]
+	g.seqs[id] = seq + 1
+	return seq
+}
+
+// SetSeqs sets the next execution count of the given nodes, so a run rebuilt
+// after a restart keeps numbering its recordings where it left off.
+func (g *ExecGraph) SetSeqs(next map[string]int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.seqs == nil {
+		g.seqs = map[string]int{}
+	}
+	for id, seq := range next {
+		g.seqs[id] = seq
+	}
+}
+
+func (g *ExecGraph) record(rec models.Recording) {
+	if g.OnRecord != nil {
+		g.OnRecord(rec)
+	}
+}
+
+// copyData returns a shallow copy of node data.
+func copyData(data map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		out[k] = v
+	}
+	return out
+}
+
+// CompareRecordings reports where a replay differs from the run it replays.
+// For every node execution the last attempt of each side is compared:
+// whether it ran at all, the branch it took, its error, the data it ran
+// with and, for nodes that were executed rather than fed, the data it
+// produced.
+func CompareRecordings(original, replay []models.Recording) []models.Divergence {
+	type key struct {
+		node string
+		seq  int
+	}
+	final := func(recs []models.Recording) (map[key]models.Recording, []key) {
+		out := map[key]models.Recording{}
+		var order []key
+		for _, rec := range recs {
+			k := key{rec.NodeID, rec.Seq}
+			prev, seen := out[k]
+			if !seen {
+				order = append(order, k)
+			}
+			if !seen || rec.Attempt >= prev.Attempt {
+				out[k] = rec
+			}
+		}
+		return out, order
+	}
+	want, order := final(original)
+	got, replayOrder := final(replay)
+
+	var divs []models.Divergence
+	for _, k := range order {
+		w := want[k]
+		g, ok := got[k]
+		if !ok {
+			divs = append(divs, models.Divergence{NodeID: k.node, Seq: k.seq, Kind: "missing",
+				Message: fmt.Sprintf("node %s (execution %d) ran originally but not in the replay", k.node, k.seq)})
+			continue
+		}
+		if w.Next != g.Next {
+			divs = append(divs, models.Divergence{NodeID: k.node, Seq: k.seq, Kind: "branch",
+				Message:  fmt.Sprintf("node %s (execution %d) took a different branch", k.node, k.seq),
+				Expected: w.Next, Actual: g.Next})
+		}
+		if w.Error != g.Error {
+			divs = append(divs, models.Divergence{NodeID: k.node, Seq: k.seq, Kind: "error",
+				Message:  fmt.Sprintf("node %s (execution %d) ended differently", k.node, k.seq),
+				Expected: w.Error, Actual: g.Error})
+		}
+		if d, ok := diffData(k.node, k.seq, "input", w.Input, g.Input); ok {
+			divs = append(divs, d)
+		}
+		if !g.Replayed {
+			if d, ok := diffData(k.node, k.seq, "output", w.Output, g.Output); ok {
+				divs = append(divs, d)
+			}
+		}
+	}
+	for _, k := range replayOrder {
+		if _, ok := want[k]; !ok {
+			divs = append(divs, models.Divergence{NodeID: k.node, Seq: k.seq, Kind: "extra",
+				Message: fmt.Sprintf("node %s (execution %d) ran in the replay but not originally", k.node, k.seq)})
+		}
+	}
+	return divs
+}
+
+// diffData compares two node data maps key by key. Values are compared by
+// their JSON form, so numbers and documents read back from Mongo compare
+// equal to the values they were written from.
+func diffData(node string, seq int, kind string, want, got map[string]interface{}) (models.Divergence, bool) {
+	keys := map[string]bool{}
+	for k := range want {
+		keys[k] = true
+	}
+	for k := range got {
+		keys[k] = true
+	}
+
+	var changed []string
+	for k := range keys {
+		if !jsonEqual(want[k], got[k]) {
+			changed = append(changed, k)
+		}
+	}
+	if len(changed) == 0 {
+		return models.Divergence{}, false
+	}
+	sort.Strings(changed)
+
+	expected := map[string]interface{}{}
+	actual := map[string]interface{}{}
+	for _, k := range changed {
+		expected[k] = want[k]
+		actual[k] = got[k]
+	}
+	return models.Divergence{
+		NodeID:   node,
+		Seq:      seq,
+		Kind:     kind,
+		Message:  fmt.Sprintf("node %s (execution %d) %s differs in %v", node, seq, kind, changed),
+		Expected: expected,
+		Actual:   actual,
+	}, true
+}
+
+func jsonEqual(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return string(ja) == string(jb)
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how often a failing node is attempted again.
+// It is read from the node's Data["retry"] object:
+//
+//	"retry": {
+//	  "maxAttempts": 4,
+//	  "initialDelayMs": 500,
+//	  "backoffFactor": 2,
+//	  "maxDelayMs": 10000,
+//	  "retryOn": ["network", "timeout", "http_5xx"]
+//	}
+//
+// retryOn lists error classes (see ErrorClass*); "any" retries every error.
+type RetryPolicy struct {
+	MaxAttempts   int
+	InitialDelay  time.Duration
+	BackoffFactor float64
+	MaxDelay      time.Duration
+	RetryOn       []string
+}
+
+// DefaultRetryOn are the error classes retried when a policy omits retryOn:
+// the transient failures of the upstream services.
+var DefaultRetryOn = []string{ErrorClassNetwork, ErrorClassTimeout, ErrorClassHTTP5xx, ErrorClassRateLimited}
+
+// noRetry is the policy of nodes without a "retry" object: a single attempt.
+var noRetry = RetryPolicy{MaxAttempts: 1}
+
+// retryPolicyFromData parses Data["retry"]. Missing fields fall back to one
+// second initial delay, factor 2 and a one minute cap.
+func retryPolicyFromData(data map[string]interface{}) (RetryPolicy, error) {
+	raw, ok := data["retry"]
+	if !ok || raw == nil {
+		return noRetry, nil
+	}
+	cfg, ok := asMap(raw)
+	if !ok {
+		return noRetry, fmt.Errorf("retry must be an object")
+	}
+
+	p := RetryPolicy{
+		MaxAttempts:   dataInt(cfg, "maxAttempts"),
+		InitialDelay:  time.Duration(dataInt(cfg, "initialDelayMs")) * time.Millisecond,
+		BackoffFactor: 2,
+		MaxDelay:      time.Minute,
+		RetryOn:       DefaultRetryOn,
+	}
+	if p.MaxAttempts < 1 {
+		p.MaxAttempts = 1
+	}
+	if _, ok := cfg["initialDelayMs"]; !ok {
+		p.InitialDelay = time.Second
+	}
+	if f, ok := toFloat(cfg["backoffFactor"]); ok {
+		if f < 1 {
+			return noRetry, fmt.Errorf("retry.backoffFactor must be >= 1")
+		}
+		p.BackoffFactor = f
+	}
+	if ms := dataInt(cfg, "maxDelayMs"); ms > 0 {
+		p.MaxDelay = time.Duration(ms) * time.Millisecond
+	}
+	if v, ok := cfg["retryOn"]; ok {
+		list, ok := asSlice(v)
+		if !ok {
+			return noRetry, fmt.Errorf("retry.retryOn must be a list of error classes")
+		}
+		p.RetryOn = []string{}
+		for _, c := range list {
+			p.RetryOn = append(p.RetryOn, strings.ToLower(fmt.Sprintf("%v", c)))
+		}
+	}
+
+	return p, nil
+}
+
+// Retryable reports whether an error of the given class may be retried.
+func (p RetryPolicy) Retryable(class string) bool {
+	for _, c := range p.RetryOn {
+		if c == "any" || c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// Delay returns how long to wait before the given attempt (2 = first retry).
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	d := float64(p.InitialDelay)
+	for i := 2; i < attempt; i++ {
+		d *= p.BackoffFactor
+		if d >= float64(p.MaxDelay) {
+			return p.MaxDelay
+		}
+	}
+	if time.Duration(d) > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return time.Duration(d)
+}
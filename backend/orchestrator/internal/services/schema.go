@@ -0,0 +1,408 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema is the subset of JSON Schema executors use to describe node data.
+// Objects accept properties that are not listed: the canvas stores its own
+// keys in node data and runs write their results back into it.
+type Schema struct {
+	Type        string             `json:"type,omitempty"` // object, array, string, number, integer, boolean; "" = any
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []interface{}      `json:"enum,omitempty"`
+	IgnoreCase  bool               `json:"ignoreCase,omitempty"` // compare string enum values case-insensitively
+	AnyOf       []*Schema          `json:"anyOf,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	MinLength   int                `json:"minLength,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	Default     interface{}        `json:"default,omitempty"`
+}
+
+// NodeSchema describes a node type. Config and Inputs are both read from the
+// node's Data: config are its settings, inputs the values it works on, which
+// are usually templates pointing at upstream nodes. Outputs are the keys the
+// executor adds to Data.
+type NodeSchema struct {
+	Config  *Schema `json:"config,omitempty"`
+	Inputs  *Schema `json:"inputs,omitempty"`
+	Outputs *Schema `json:"outputs,omitempty"`
+}
+
+// SchemaProvider is implemented by executors that declare their NodeSchema.
+type SchemaProvider interface {
+	Schema() NodeSchema
+}
+
+// DataValidator is implemented by executors that check their Data beyond
+// what their schema can express, such as compiling an expression.
+type DataValidator interface {
+	ValidateData(nodeID string, data map[string]interface{}) []FieldError
+}
+
+// EdgeValidator is implemented by executors whose outgoing edges must match
+// their configuration, such as a switch and its cases.
+type EdgeValidator interface {
+	ValidateEdges(n *ExecNode) []FieldError
+}
+
+// FieldError is a validation failure of one field of one node, or of the
+// run input when Node is empty.
+type FieldError struct {
+	Node    string `json:"node,omitempty"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	if e.Node == "" {
+		return fmt.Sprintf("%s: %s", e.Field, e.Message)
+	}
+	return fmt.Sprintf("node %s: %s: %s", e.Node, e.Field, e.Message)
+}
+
+// ValidationErrors is returned when one or more nodes do not match their
+// schema.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func atLeast(v float64) *float64 { return &v }
+
+// commonSchema covers the keys the engine itself reads from every node.
+var commonSchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"timeoutSeconds": {Type: "number", Minimum: atLeast(0), Description: "per-attempt deadline"},
+		"retry": {
+			Type: "object",
+			Properties: map[string]*Schema{
+				"maxAttempts":    {Type: "integer", Minimum: atLeast(1)},
+				"initialDelayMs": {Type: "integer", Minimum: atLeast(0)},
+				"backoffFactor":  {Type: "number", Minimum: atLeast(1)},
+				"maxDelayMs":     {Type: "integer", Minimum: atLeast(0)},
+				"retryOn": {Type: "array", Items: &Schema{Type: "string", Enum: []interface{}{
+					"any", ErrorClassTimeout, ErrorClassNetwork, ErrorClassHTTP5xx, ErrorClassHTTP4xx,
+					ErrorClassRateLimited, ErrorClassConfig, ErrorClassError,
+				}}},
+			},
+		},
+	},
+}
+
+// SchemaFor returns the schema declared by the executor of nodeType.
+func SchemaFor(nodeType string) (NodeSchema, bool) {
+	e, ok := executors[nodeType]
+	if !ok {
+		return NodeSchema{}, false
+	}
+	p, ok := e.(SchemaProvider)
+	if !ok {
+		return NodeSchema{}, false
+	}
+	return p.Schema(), true
+}
+
+// Schemas returns the schema of every registered node type.
+func Schemas() map[string]NodeSchema {
+	out := map[string]NodeSchema{}
+	for t := range executors {
+		if s, ok := SchemaFor(t); ok {
+			out[t] = s
+		} else {
+			out[t] = NodeSchema{}
+		}
+	}
+	return out
+}
+
+// ValidateNode checks a node's Data against the engine's common keys and its
+// type's config and input schemas. Values that are templates are only
+// checked once resolved, so they pass here.
+func ValidateNode(nodeID, nodeType string, data map[string]interface{}) []FieldError {
+	var errs []FieldError
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	commonSchema.validate(nodeID, "data", data, &errs)
+	if s, ok := SchemaFor(nodeType); ok {
+		if s.Config != nil {
+			s.Config.validate(nodeID, "data", data, &errs)
+		}
+		if s.Inputs != nil {
+			s.Inputs.validate(nodeID, "data", data, &errs)
+		}
+	}
+	if v, ok := executors[nodeType].(DataValidator); ok {
+		errs = append(errs, v.ValidateData(nodeID, data)...)
+	}
+	return errs
+}
+
+// ValidateGraph validates every node of g and, where the node type cares,
+// its outgoing edges. It returns ValidationErrors, or
+// nil if all nodes are valid.
+func ValidateGraph(g *ExecGraph) error {
+	ids := make([]string, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var errs ValidationErrors
+	for _, id := range ids {
+		n := g.Nodes[id]
+		errs = append(errs, ValidateNode(n.ID, n.Type, n.Data)...)
+		if v, ok := executors[n.Type].(EdgeValidator); ok {
+			errs = append(errs, v.ValidateEdges(n)...)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateOutputs checks what an executor wrote against its output schema.
+func validateOutputs(n *ExecNode) []FieldError {
+	s, ok := SchemaFor(n.Type)
+	if !ok || s.Outputs == nil {
+		return nil
+	}
+	var errs []FieldError
+	s.Outputs.validate(n.ID, "data", n.Data, &errs)
+	return errs
+}
+
+// validate appends the ways v does not match s to errs. For node data
+// (node != "") values that are templates are left to run time and numbers
+// may be typed as strings; run inputs (node == "") must be JSON numbers.
+func (s *Schema) validate(node, path string, v interface{}, errs *[]FieldError) {
+	if s == nil || v == nil {
+		return
+	}
+	if str, ok := v.(string); ok && node != "" && templatePattern.MatchString(str) {
+		return
+	}
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{Node: node, Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.AnyOf) > 0 {
+		matched := false
+		var first []FieldError
+		for i, alt := range s.AnyOf {
+			var altErrs []FieldError
+			alt.validate(node, path, v, &altErrs)
+			if len(altErrs) == 0 {
+				matched = true
+				break
+			}
+			if i == 0 {
+				first = altErrs
+			}
+		}
+		if !matched {
+			if types := anyOfTypes(s.AnyOf); types != "" {
+				fail("must be of type %s", types)
+			} else {
+				*errs = append(*errs, first...)
+			}
+			return
+		}
+	}
+
+	if !s.hasType(v, node == "") {
+		fail("must be of type %s", s.Type)
+		return
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v, s.IgnoreCase) {
+		fail("must be one of %v", s.Enum)
+	}
+
+	if f, ok := toFloat(v); ok && s.Type != "" && s.Type != "string" {
+		if s.Minimum != nil && f < *s.Minimum {
+			fail("must be >= %v", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			fail("must be <= %v", *s.Maximum)
+		}
+	}
+
+	if str, ok := v.(string); ok {
+		if len(str) < s.MinLength {
+			if s.MinLength == 1 {
+				fail("must not be empty")
+			} else {
+				fail("must be at least %d characters", s.MinLength)
+			}
+		}
+		if s.Pattern != "" {
+			if re, err := regexp.Compile(s.Pattern); err == nil && !re.MatchString(str) {
+				fail("must match %s", s.Pattern)
+			}
+		}
+	}
+
+	if m, ok := asMap(v); ok {
+		for _, key := range s.Required {
+			if val, ok := m[key]; !ok || val == nil {
+				*errs = append(*errs, FieldError{Node: node, Field: path + "." + key, Message: "is required"})
+			}
+		}
+		keys := make([]string, 0, len(s.Properties))
+		for key := range s.Properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if val, ok := m[key]; ok {
+				s.Properties[key].validate(node, path+"."+key, val, errs)
+			}
+		}
+	}
+
+	if list, ok := asSlice(v); ok && s.Items != nil {
+		for i, item := range list {
+			s.Items.validate(node, fmt.Sprintf("%s[%d]", path, i), item, errs)
+		}
+	}
+}
+
+// ParseSchema reads a JSON Schema document, such as a workflow's input
+// schema, into the subset Schema supports.
+func ParseSchema(raw map[string]interface{}) (*Schema, error) {
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var s Schema
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if err := s.check("schema"); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// check rejects types and patterns the validator does not understand.
+func (s *Schema) check(path string) error {
+	switch s.Type {
+	case "", "object", "array", "string", "number", "integer", "boolean":
+	default:
+		return fmt.Errorf("%s: unknown type %q", path, s.Type)
+	}
+	if s.Pattern != "" {
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			return fmt.Errorf("%s: invalid pattern: %v", path, err)
+		}
+	}
+	for key, p := range s.Properties {
+		if err := p.check(path + ".properties." + key); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		if err := s.Items.check(path + ".items"); err != nil {
+			return err
+		}
+	}
+	for i, alt := range s.AnyOf {
+		if err := alt.check(fmt.Sprintf("%s.anyOf[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateInput checks a run input against the workflow's input schema and
+// returns it with the defaults of missing top-level properties filled in.
+func ValidateInput(schema *Schema, input map[string]interface{}) (map[string]interface{}, error) {
+	out := map[string]interface{}{}
+	for k, v := range input {
+		out[k] = v
+	}
+	for key, p := range schema.Properties {
+		if _, ok := out[key]; !ok && p.Default != nil {
+			out[key] = p.Default
+		}
+	}
+
+	var errs ValidationErrors
+	schema.validate("", "input", out, (*[]FieldError)(&errs))
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return out, nil
+}
+
+// hasType reports whether v is of s's type. Unless strict, a number or
+// integer may also be a numeric string.
+func (s *Schema) hasType(v interface{}, strict bool) bool {
+	switch s.Type {
+	case "":
+		return true
+	case "object":
+		_, ok := asMap(v)
+		return ok
+	case "array":
+		_, ok := asSlice(v)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "number", "integer":
+		if _, isString := v.(string); isString && strict {
+			return false
+		}
+		f, ok := toFloat(v)
+		if s.Type == "integer" {
+			return ok && f == float64(int64(f))
+		}
+		return ok
+	}
+	return false
+}
+
+// anyOfTypes returns "boolean or string" for alternatives that only differ
+// in type, or "" if some alternative is not a plain type.
+func anyOfTypes(alts []*Schema) string {
+	types := make([]string, len(alts))
+	for i, alt := range alts {
+		if alt.Type == "" {
+			return ""
+		}
+		types[i] = alt.Type
+	}
+	return strings.Join(types, " or ")
+}
+
+func inEnum(enum []interface{}, v interface{}, ignoreCase bool) bool {
+	for _, e := range enum {
+		a, b := fmt.Sprintf("%v", e), fmt.Sprintf("%v", v)
+		if a == b || ignoreCase && strings.EqualFold(a, b) {
+			return true
+		}
+	}
+	return false
+}
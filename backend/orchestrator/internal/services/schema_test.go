@@ -0,0 +1,46 @@
+package services
+
+import "testing"
+
+func TestValidateInputNumbers(t *testing.T) {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{
+		"amount": {Type: "integer"},
+		"ratio":  {Type: "number"},
+	}}
+
+	for _, tc := range []struct {
+		name  string
+		input map[string]interface{}
+		ok    bool
+	}{
+		{"json integer", map[string]interface{}{"amount": 12.0}, true},
+		{"bson integer", map[string]interface{}{"amount": int32(12)}, true},
+		{"json number", map[string]interface{}{"ratio": 0.5}, true},
+		{"fractional integer", map[string]interface{}{"amount": 1.5}, false},
+		{"numeric string integer", map[string]interface{}{"amount": "12"}, false},
+		{"numeric string number", map[string]interface{}{"ratio": "0.5"}, false},
+		{"padded exponent", map[string]interface{}{"amount": " 1e3 "}, false},
+		{"NaN string", map[string]interface{}{"ratio": "NaN"}, false},
+		{"boolean", map[string]interface{}{"ratio": true}, false},
+	} {
+		out, err := ValidateInput(schema, tc.input)
+		if tc.ok && err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+		if !tc.ok && err == nil {
+			t.Errorf("%s: accepted %v", tc.name, out)
+		}
+	}
+}
+
+func TestValidateDataNumericStrings(t *testing.T) {
+	// Node data typed into the canvas may hold numbers as strings.
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{
+		"sleepMs": {Type: "number"},
+	}}
+	var errs []FieldError
+	schema.validate("task1", "data", map[string]interface{}{"sleepMs": "250"}, &errs)
+	if len(errs) > 0 {
+		t.Errorf("numeric string rejected in node data: %v", errs)
+	}
+}
@@ -0,0 +1,393 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Davanesh/auto-orchestrator/internal/secrets"
+)
+
+// Templates let a node's Data refer to the rest of the run:
+//
+//	{{ nodes.ai1.output }}        result of node ai1 (its output, else input)
+//	{{ nodes.ai1.status }}        its status
+//	{{ nodes.ai1.data.key }}      any key of its data (also {{ nodes.ai1.key }})
+//	{{ trigger.body.message }}    the payload the run was started with
+//	{{ vars.name }}               run variables
+//	{{ secrets.name }}            a stored secret (node data only)
+//
+// Paths are dotted and may index lists: {{ nodes.http1.output.items[0].id }}.
+// A value that is a single template keeps the referenced value's type; inside
+// a longer string the value is rendered as text (objects and lists as JSON).
+// Unknown roots are an error, missing values resolve to null / "".
+//
+// Run variables are the start node's Data["vars"] overlaid with the "vars"
+// object of the run input.
+//
+// Secrets are decrypted only for the node that references them, right before
+// it runs; they are not part of TemplateContext, so conditions, mappings and
+// workflow outputs cannot read them. Every decrypted value is registered with
+// package secrets for redaction.
+
+var templatePattern = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+
+var pathSegment = regexp.MustCompile(`[^.\[\]]+|\[\d+\]`)
+
+// engineKeys are written by the engine or by executors, never by the user,
+// so they are not treated as templates. A WhatsApp reply that happens to
+// contain "{{" must not be expanded.
+var engineKeys = map[string]bool{
+	"output":   true,
+	"error":    true,
+	"loop":     true,
+	"inputs":   true,
+	"arrivals": true,
+}
+
+// expressionKeys hold expressions that are evaluated against the run context
+// directly (decision and loop conditions, switch cases). Resolving templates
+// in them would paste run data, such as a trigger body or a WhatsApp reply,
+// into the expression source.
+var expressionKeys = map[string]bool{
+	"condition": true,
+	"cases":     true,
+}
+
+// TemplateContext returns the values templates are resolved against.
+func (g *ExecGraph) TemplateContext() map[string]interface{} {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	nodes := make(map[string]interface{}, len(g.Nodes))
+	for id, n := range g.Nodes {
+		// Copy: join and catch write into node data in place. The data keys
+		// are also reachable directly, as in nodes.wait1.input.
+		data := make(map[string]interface{}, len(n.Data))
+		entry := make(map[string]interface{}, len(n.Data)+6)
+		for k, v := range n.Data {
+			data[k] = v
+			entry[k] = v
+		}
+		result, _ := n.result()
+		entry["id"] = n.ID
+		entry["label"] = n.Label
+		entry["type"] = n.Type
+		entry["status"] = n.Status
+		entry["output"] = result
+		entry["data"] = data
+		nodes[id] = entry
+	}
+
+	vars := map[string]interface{}{}
+	if start, ok := g.Nodes[g.Start]; ok {
+		if m, ok := asMap(start.Data["vars"]); ok {
+			for k, v := range m {
+				vars[k] = v
+			}
+		}
+	}
+	if m, ok := asMap(g.Input["vars"]); ok {
+		for k, v := range m {
+			vars[k] = v
+		}
+	}
+
+	return map[string]interface{}{
+		"nodes":   nodes,
+		"trigger": map[string]interface{}{"body": g.Input},
+		"vars":    vars,
+	}
+}
+
+// ContextNames are the top-level names of TemplateContext, the only names
+// condition expressions may use.
+var ContextNames = []string{"nodes", "trigger", "vars"}
+
+// CheckTemplates reports a template in v, which may be nested in objects and
+// lists, whose root is not part of the run context.
+func CheckTemplates(v interface{}) error {
+	scope := make(map[string]interface{}, len(ContextNames))
+	for _, name := range ContextNames {
+		scope[name] = nil
+	}
+	_, err := resolveValue(v, scope)
+	return err
+}
+
+// SecretResolver returns the plaintext of the named secrets. It fails with a
+// config error if one of them does not exist. The api package provides it,
+// since it owns the secrets collection.
+type SecretResolver func(ctx context.Context, names []string) (map[string]string, error)
+
+var secretResolver SecretResolver
+
+// SetSecretResolver installs the function used to resolve {{ secrets.name }}.
+func SetSecretResolver(r SecretResolver) {
+	secretResolver = r
+}
+
+// secretRefs returns the names of the secrets referenced by templates in v.
+func secretRefs(v interface{}, names map[string]bool) {
+	if s, ok := v.(string); ok {
+		for _, m := range templatePattern.FindAllStringSubmatch(s, -1) {
+			segments := pathSegment.FindAllString(strings.TrimSpace(m[1]), -1)
+			if len(segments) > 1 && segments[0] == "secrets" {
+				names[segments[1]] = true
+			}
+		}
+		return
+	}
+	if m, ok := asMap(v); ok {
+		for _, e := range m {
+			secretRefs(e, names)
+		}
+		return
+	}
+	if list, ok := asSlice(v); ok {
+		for _, e := range list {
+			secretRefs(e, names)
+		}
+	}
+}
+
+// secretValue returns the plaintext of one secret, for executors that take
+// a secret's name rather than a template.
+func secretValue(ctx context.Context, name string) (string, error) {
+	if secretResolver == nil {
+		return "", Classified(ErrorClassConfig, errors.New("secrets are not available"))
+	}
+	values, err := secretResolver(ctx, []string{name})
+	if err != nil {
+		return "", err
+	}
+	secrets.Track(values[name])
+	return values[name], nil
+}
+
+// nodeScope returns the scope the templates of n are resolved against: the
+// run context plus the secrets n references.
+func nodeScope(ctx context.Context, n *ExecNode, g *ExecGraph) (map[string]interface{}, error) {
+	scope := g.TemplateContext()
+
+	refs := map[string]bool{}
+	for k, v := range n.Data {
+		if !engineKeys[k] && !expressionKeys[k] {
+			secretRefs(v, refs)
+		}
+	}
+	if len(refs) == 0 {
+		return scope, nil
+	}
+	if secretResolver == nil {
+		return nil, Classified(ErrorClassConfig, errors.New("secrets are not available"))
+	}
+
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values, err := secretResolver(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make(map[string]interface{}, len(values))
+	for name, v := range values {
+		secrets.Track(v)
+		resolved[name] = v
+	}
+	scope["secrets"] = resolved
+	return scope, nil
+}
+
+// templated is a Data value that was rewritten by resolveNode.
+type templated struct {
+	original interface{}
+	resolved interface{}
+}
+
+// resolveNode replaces the templates in n.Data with their values. It returns
+// the rewritten keys so restoreTemplates can put the templates back once the
+// executor is done, keeping the node's configuration intact for retries,
+// loop iterations and the stored run.
+func resolveNode(ctx context.Context, n *ExecNode, g *ExecGraph) (map[string]templated, error) {
+	var scope map[string]interface{}
+	rewritten := map[string]templated{}
+
+	for k, v := range n.Data {
+		if engineKeys[k] || expressionKeys[k] || !hasTemplate(v) {
+			continue
+		}
+		if scope == nil {
+			var err error
+			if scope, err = nodeScope(ctx, n, g); err != nil {
+				return nil, fmt.Errorf("node %s: %w", n.ID, err)
+			}
+		}
+
+		resolved, err := resolveValue(v, scope)
+		if err != nil {
+			return nil, Classified(ErrorClassConfig, fmt.Errorf("node %s: %s: %w", n.ID, k, err))
+		}
+		n.Data[k] = resolved
+		rewritten[k] = templated{original: v, resolved: resolved}
+	}
+	return rewritten, nil
+}
+
+// restoreTemplates undoes resolveNode for every key the executor left alone.
+func restoreTemplates(n *ExecNode, rewritten map[string]templated) {
+	for k, t := range rewritten {
+		if reflect.DeepEqual(n.Data[k], t.resolved) {
+			n.Data[k] = t.original
+		}
+	}
+}
+
+// hasTemplate reports whether v, or anything nested in it, contains "{{".
+func hasTemplate(v interface{}) bool {
+	if s, ok := v.(string); ok {
+		return templatePattern.MatchString(s)
+	}
+	if m, ok := asMap(v); ok {
+		for _, e := range m {
+			if hasTemplate(e) {
+				return true
+			}
+		}
+		return false
+	}
+	if list, ok := asSlice(v); ok {
+		for _, e := range list {
+			if hasTemplate(e) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveValue resolves the templates in v, walking nested objects and lists.
+func resolveValue(v interface{}, scope map[string]interface{}) (interface{}, error) {
+	if s, ok := v.(string); ok {
+		return RenderTemplate(s, scope)
+	}
+	if m, ok := asMap(v); ok {
+		out := make(map[string]interface{}, len(m))
+		for k, e := range m {
+			r, err := resolveValue(e, scope)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	}
+	if list, ok := asSlice(v); ok {
+		out := make([]interface{}, len(list))
+		for i, e := range list {
+			r, err := resolveValue(e, scope)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// RenderTemplate resolves the templates in s against scope. If s is exactly
+// one template the referenced value is returned as is.
+func RenderTemplate(s string, scope map[string]interface{}) (interface{}, error) {
+	matches := templatePattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s, nil
+	}
+
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(s) {
+		return LookupPath(scope, s[matches[0][2]:matches[0][3]])
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		v, err := LookupPath(scope, s[m[2]:m[3]])
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(s[last:m[0]])
+		b.WriteString(stringify(v))
+		last = m[1]
+	}
+	b.WriteString(s[last:])
+	return b.String(), nil
+}
+
+// LookupPath follows a dotted path such as "nodes.ai1.output" or
+// "trigger.body.items[0]" through scope. The first segment must name a key
+// of scope; missing keys further down yield nil.
+func LookupPath(scope map[string]interface{}, path string) (interface{}, error) {
+	segments := pathSegment.FindAllString(strings.TrimSpace(path), -1)
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("empty template")
+	}
+
+	cur, ok := scope[segments[0]]
+	if !ok {
+		return nil, fmt.Errorf("unknown template root %q in {{ %s }}", segments[0], path)
+	}
+
+	for _, seg := range segments[1:] {
+		if cur == nil {
+			return nil, nil
+		}
+		if strings.HasPrefix(seg, "[") {
+			list, ok := asSlice(cur)
+			if !ok {
+				return nil, nil
+			}
+			i, _ := strconv.Atoi(seg[1 : len(seg)-1])
+			if i >= len(list) {
+				return nil, nil
+			}
+			cur = list[i]
+			continue
+		}
+		m, ok := asMap(cur)
+		if !ok {
+			return nil, nil
+		}
+		cur = m[seg]
+	}
+	return cur, nil
+}
+
+// stringify renders a template value inside a longer string.
+func stringify(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	}
+	if _, ok := asMap(v); ok {
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	if _, ok := asSlice(v); ok {
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	return fmt.Sprintf("%v", v)
+}
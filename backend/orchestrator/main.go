@@ -9,6 +9,7 @@ import (
 	"github.com/Davanesh/auto-orchestrator/internal/api"
 	"github.com/Davanesh/auto-orchestrator/internal/db"
 	"github.com/Davanesh/auto-orchestrator/internal/executors" // IMPORTANT: kept for webhook handler
+	"github.com/Davanesh/auto-orchestrator/internal/secrets"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -24,14 +25,25 @@ func main() {
 		log.Println("⚠️  Warning: .env file not found, using system env")
 	}
 
+	// Mask decrypted secret values in everything written through log.
+	log.SetOutput(secrets.Writer(os.Stderr))
+
 	log.Println("🔑 OPENAI_KEY Loaded:", os.Getenv("OPENAI_API_KEY") != "")
 	log.Println("🔑 TWILIO SID Loaded:", os.Getenv("TWILIO_SID") != "")
-	log.Println("🔑 ALLOWED_WHATSAPP_NUMBER:", os.Getenv("ALLOWED_WHATSAPP_NUMBER"))
+	log.Println("🔑 ALLOWED_WHATSAPP_NUMBER Loaded:", os.Getenv("ALLOWED_WHATSAPP_NUMBER") != "")
+	log.Println("🔑 TELEGRAM_BOT_TOKEN Loaded:", os.Getenv("TELEGRAM_BOT_TOKEN") != "")
+	if err := secrets.Configured(); err != nil {
+		log.Println("⚠️  Secrets store disabled:", err)
+	}
 
 	// -------------------------------
 	// 2) Initialize Database
 	// -------------------------------
 	db.InitDB()
+	api.EnsureIndexes()
+
+	// Pick up runs that were in flight when the process last stopped.
+	api.ResumeRuns()
 
 	// -------------------------------
 	// 3) Setup Gin Server
@@ -59,6 +71,8 @@ func main() {
 	// 5) Workflow Routes
 	// -------------------------------
 	api.RegisterWorkflowRoutes(r)
+	api.RegisterRunRoutes(r)
+	api.RegisterSecretRoutes(r)
 
 	// -------------------------------
 	// 6) WhatsApp Webhook Route
@@ -69,7 +83,12 @@ func main() {
 	})
 
 	// -------------------------------
-	// 7) Start the Server (async)
+	// 7) Telegram Webhook Route
+	// -------------------------------
+	r.POST("/webhook/telegram", executors.HandleTelegramWebhookGin)
+
+	// -------------------------------
+	// 8) Start the Server (async)
 	// -------------------------------
 	go func() {
 		log.Println("🚀 Orchestrator running on port 8080...")
@@ -79,7 +98,7 @@ func main() {
 	}()
 
 	// -------------------------------
-	// 8) Print all Registered Routes
+	// 9) Print all Registered Routes
 	// -------------------------------
 	for _, route := range r.Routes() {
 		fmt.Println(route.Method, route.Path)
@@ -263,7 +263,7 @@ export default function CanvasArea() {
     try {
       const res = await axios.post(`${API_BASE}/workflows/${id}/run`, {});
       console.log("Run response:", res.data);
-      alert("Run finished — check console or Execution Logs UI.");
+      alert("Run started — id: " + (res.data.runId || ""));
     } catch (err) {
       console.error("Run failed:", err.response?.data || err.message);
       alert("Run failed: " + (err.response?.data?.error || err.message));